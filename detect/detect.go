@@ -0,0 +1,241 @@
+// Package detect identifies the service behind an open port by sending probes
+// and matching the responses against a signature database.
+//
+// Signature files use the format of nmap-service-probes, see
+// https://nmap.org/book/vscan-fileformat.html. Probe, ports, rarity,
+// totalwaitms, match and softmatch lines are supported, other directives are
+// ignored.
+package detect
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultIntensity limits the probes sent to ports they do not list
+const DefaultIntensity = 7
+
+// DB is a signature database
+type DB struct {
+	Probes []*Probe
+	// Intensity is the highest rarity of probes sent to ports they do not
+	// list, from 0 (only the NULL probe) to 9 (all probes)
+	Intensity int
+	// Skipped counts matches whose regular expression could not be compiled
+	Skipped int
+}
+
+// Probe is a payload sent to a service and the matches for its response
+type Probe struct {
+	Protocol string
+	Name     string
+	Payload  []byte
+	Ports    map[int]bool
+	Rarity   int
+	Wait     time.Duration
+	Matches  []*Match
+}
+
+// Match is a pattern identifying a service from a probe response
+type Match struct {
+	Service string
+	Soft    bool
+	Re      *regexp.Regexp
+	// version info templates, $1 to $9 are replaced by submatches
+	Product, Version, Info, Hostname, OS, Device string
+}
+
+// Service identified by a match
+type Service struct {
+	Name     string `json:"name"`
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Info     string `json:"info,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Device   string `json:"device,omitempty"`
+	// Probe is the name of the probe whose response matched
+	Probe string `json:"probe"`
+}
+
+func (s *Service) String() string {
+	str := s.Name
+	if s.Product != "" {
+		str += " " + s.Product
+	}
+	if s.Version != "" {
+		str += " " + s.Version
+	}
+	if s.Info != "" {
+		str += " (" + s.Info + ")"
+	}
+	return str
+}
+
+// Dial opens a new connection to the port being detected
+type Dial func(ctx context.Context) (net.Conn, error)
+
+// Detect the service on a TCP port. greeting is what the service sent after
+// connecting and is used as response of the NULL probe. Other probes are sent
+// on new connections opened by dial, responses are read for at most wait
+// unless the probe sets its own wait time.
+func (db *DB) Detect(ctx context.Context, port int, greeting []byte, dial Dial, wait time.Duration) *Service {
+	var soft *Service
+	for _, p := range db.order(port) {
+		if ctx.Err() != nil {
+			break
+		}
+		resp := greeting
+		if p.Name != "NULL" {
+			var ok bool
+			if resp, ok = p.send(ctx, dial, wait); !ok {
+				continue
+			}
+		}
+		if len(resp) == 0 {
+			continue
+		}
+		svc := p.match(resp, soft)
+		if svc == nil {
+			continue
+		}
+		if !svc.soft {
+			return &svc.Service
+		}
+		if soft == nil {
+			soft = &svc.Service
+		}
+	}
+	return soft
+}
+
+// GreetingWait returns how long to wait for the greeting of a service: the
+// totalwaitms of the NULL probe, or wait if it sets none
+func (db *DB) GreetingWait(wait time.Duration) time.Duration {
+	for _, p := range db.Probes {
+		if p.Protocol == "TCP" && p.Name == "NULL" && p.Wait > 0 {
+			return p.Wait
+		}
+	}
+	return wait
+}
+
+// order returns the TCP probes to send to port: NULL first, then the probes
+// listing port, then the others up to the intensity
+func (db *DB) order(port int) []*Probe {
+	var listed, other []*Probe
+	for _, p := range db.Probes {
+		switch {
+		case p.Protocol != "TCP":
+		case p.Name == "NULL":
+			listed = append([]*Probe{p}, listed...)
+		case p.Ports[port]:
+			listed = append(listed, p)
+		case p.Rarity <= db.Intensity:
+			other = append(other, p)
+		}
+	}
+	return append(listed, other...)
+}
+
+// send the probe on a new connection and read the response
+func (p *Probe) send(ctx context.Context, dial Dial, wait time.Duration) ([]byte, bool) {
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+
+	if p.Wait > 0 {
+		wait = p.Wait
+	}
+	conn.SetDeadline(time.Now().Add(wait))
+	if _, err := conn.Write(p.Payload); err != nil {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	chunk := make([]byte, 4096)
+	for buf.Len() < 64*1024 {
+		n, err := conn.Read(chunk)
+		buf.Write(chunk[:n])
+		if err != nil {
+			break
+		}
+	}
+	return buf.Bytes(), true
+}
+
+type matched struct {
+	Service
+	soft bool
+}
+
+// match resp against the matches of p. After a soft match only matches of
+// the same service are tried.
+func (p *Probe) match(resp []byte, soft *Service) *matched {
+	// match bytes as latin-1 runes so \xNN patterns match raw bytes
+	s := latin1(resp)
+	for _, m := range p.Matches {
+		if soft != nil && m.Service != soft.Name {
+			continue
+		}
+		sub := m.Re.FindStringSubmatch(s)
+		if sub == nil {
+			continue
+		}
+		return &matched{
+			Service: Service{
+				Name:     m.Service,
+				Product:  expand(m.Product, sub),
+				Version:  expand(m.Version, sub),
+				Info:     expand(m.Info, sub),
+				Hostname: expand(m.Hostname, sub),
+				OS:       expand(m.OS, sub),
+				Device:   expand(m.Device, sub),
+				Probe:    p.Name,
+			},
+			soft: m.Soft,
+		}
+	}
+	return nil
+}
+
+var submatchRe = regexp.MustCompile(`\$([1-9])`)
+
+// expand replaces $1 to $9 in template by the submatches
+func expand(template string, sub []string) string {
+	if template == "" {
+		return ""
+	}
+	s := submatchRe.ReplaceAllStringFunc(template, func(ref string) string {
+		i, _ := strconv.Atoi(ref[1:])
+		if i >= len(sub) {
+			return ""
+		}
+		return printable(sub[i])
+	})
+	return strings.TrimSpace(s)
+}
+
+func latin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+// printable drops the non printable characters of s
+func printable(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,129 @@
+package detect
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefault(t *testing.T) {
+	db := Default()
+	if db.Skipped != 0 || len(db.Probes) == 0 || db.Probes[0].Name != "NULL" {
+		t.Fatalf("bad default database: %d probes, %d skipped", len(db.Probes), db.Skipped)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	db, err := Load(strings.NewReader(`
+# comment
+Probe TCP NULL q||
+match ssh m|^SSH-([\d.]+)-OpenSSH_(\S+)\r?\n| p/OpenSSH/ v/$2/ i/protocol $1/ cpe:/a:openbsd:openssh:$2/a
+softmatch ftp m/^220 /
+match skipped m|(?<=lookbehind)|
+Probe TCP Bin q|\x00\x01\r\n|
+ports 80,8000-8002
+rarity 3
+totalwaitms 500
+match bin m=^\x00\xff=s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Skipped != 1 || len(db.Probes) != 2 {
+		t.Fatalf("got %d probes, %d skipped", len(db.Probes), db.Skipped)
+	}
+	p := db.Probes[1]
+	if string(p.Payload) != "\x00\x01\r\n" || !p.Ports[8001] || p.Rarity != 3 || p.Wait != 500*time.Millisecond {
+		t.Errorf("bad probe %+v", p)
+	}
+
+	svc := db.Probes[0].match([]byte("SSH-2.0-OpenSSH_9.6p1\r\n"), nil)
+	if svc == nil || svc.soft || svc.String() != "ssh OpenSSH 9.6p1 (protocol 2.0)" {
+		t.Errorf("got %+v", svc)
+	}
+	if svc := db.Probes[0].match([]byte("220 hello\r\n"), nil); svc == nil || !svc.soft || svc.Name != "ftp" {
+		t.Errorf("got %+v", svc)
+	}
+	if svc := p.match([]byte{0, 0xff}, nil); svc == nil || svc.Name != "bin" {
+		t.Errorf("got %+v", svc)
+	}
+
+	// the NULL probe sets no wait, the greeting is read for the default
+	if wait := db.GreetingWait(time.Second); wait != time.Second {
+		t.Errorf("greeting wait %v", wait)
+	}
+	if wait := Default().GreetingWait(time.Second); wait != 5*time.Second {
+		t.Errorf("default greeting wait %v", wait)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	for _, s := range []string{
+		"match ssh m|x|",
+		"Probe TCP NULL q|",
+		"Probe TCP X q|\\xZZ|",
+		"Probe TCP NULL q||\nmatch ssh m|x",
+		"Probe TCP NULL q||\nports 10-1",
+		"Probe TCP NULL q||\nrarity x",
+	} {
+		if _, err := Load(strings.NewReader(s)); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+// serve answers on a local port with handle and returns a Dial for it
+func serve(t *testing.T, handle func(net.Conn)) (int, Dial) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				handle(conn)
+				conn.Close()
+			}()
+		}
+	}()
+	dial := func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", l.Addr().String())
+	}
+	return l.Addr().(*net.TCPAddr).Port, dial
+}
+
+func TestDetect(t *testing.T) {
+	db := Default()
+	ctx := context.Background()
+
+	port, dial := serve(t, func(net.Conn) {})
+	svc := db.Detect(ctx, port, []byte("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n"), dial, 100*time.Millisecond)
+	if svc == nil || svc.Product != "OpenSSH" || svc.Version != "9.6p1 Ubuntu 3ubuntu13" {
+		t.Errorf("ssh: got %+v", svc)
+	}
+
+	port, dial = serve(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if strings.HasPrefix(line, "GET / ") {
+			conn.Write([]byte("HTTP/1.1 200 OK\r\nServer: nginx/1.25.3\r\nContent-Length: 0\r\n\r\n"))
+		}
+	})
+	svc = db.Detect(ctx, port, nil, dial, 200*time.Millisecond)
+	if svc == nil || svc.Name != "http" || svc.Product != "nginx" || svc.Version != "1.25.3" || svc.Probe != "GetRequest" {
+		t.Errorf("http: got %+v", svc)
+	}
+
+	port, dial = serve(t, func(net.Conn) {})
+	if svc := db.Detect(ctx, port, nil, dial, 50*time.Millisecond); svc != nil {
+		t.Errorf("silent: got %+v", svc)
+	}
+}
@@ -0,0 +1,253 @@
+package detect
+
+import (
+	"bufio"
+	_ "embed" // default signatures
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed probes.txt
+var defaultProbes string
+
+// Default returns the signature database shipped with netscan
+func Default() *DB {
+	db, err := Load(strings.NewReader(defaultProbes))
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// LoadFile loads a signature database from path
+func LoadFile(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	db, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return db, nil
+}
+
+// Load a signature database in nmap-service-probes format. Matches with
+// regular expressions unsupported by Go are skipped and counted in
+// DB.Skipped.
+func Load(r io.Reader) (*DB, error) {
+	db := &DB{Intensity: DefaultIntensity}
+	var probe *Probe
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		directive, args, _ := strings.Cut(line, " ")
+		args = strings.TrimSpace(args)
+
+		if directive == "Probe" {
+			p, err := parseProbe(args)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			probe = p
+			db.Probes = append(db.Probes, p)
+			continue
+		}
+		if probe == nil {
+			if directive == "Exclude" {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %s before first Probe", n, directive)
+		}
+
+		var err error
+		switch directive {
+		case "match", "softmatch":
+			var m *Match
+			m, err = parseMatch(args, directive == "softmatch")
+			if err == errUnsupported {
+				db.Skipped++
+				err = nil
+			} else if err == nil {
+				probe.Matches = append(probe.Matches, m)
+			}
+		case "ports":
+			err = parsePorts(args, probe.Ports)
+		case "rarity":
+			probe.Rarity, err = strconv.Atoi(args)
+		case "totalwaitms":
+			var ms int
+			ms, err = strconv.Atoi(args)
+			probe.Wait = time.Duration(ms) * time.Millisecond
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+var errUnsupported = errors.New("unsupported regular expression")
+
+// parseProbe parses "TCP GetRequest q|GET / HTTP/1.0\r\n\r\n|"
+func parseProbe(args string) (*Probe, error) {
+	f := strings.SplitN(args, " ", 3)
+	if len(f) < 3 || (f[0] != "TCP" && f[0] != "UDP") || !strings.HasPrefix(f[2], "q") {
+		return nil, fmt.Errorf("invalid Probe %q", args)
+	}
+	payload, _, err := delimited(f[2][1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid Probe %q: %v", args, err)
+	}
+	data, err := unescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Probe %q: %v", args, err)
+	}
+	return &Probe{
+		Protocol: f[0],
+		Name:     f[1],
+		Payload:  data,
+		Ports:    make(map[int]bool),
+		Rarity:   1,
+	}, nil
+}
+
+// parseMatch parses "ssh m|^SSH-([\d.]+)-OpenSSH_(\S+)| p/OpenSSH/ v/$2/"
+func parseMatch(args string, soft bool) (*Match, error) {
+	service, rest, _ := strings.Cut(args, " ")
+	if !strings.HasPrefix(rest, "m") {
+		return nil, fmt.Errorf("invalid match %q", args)
+	}
+	pattern, rest, err := delimited(rest[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid match %q: %v", args, err)
+	}
+	flags := ""
+	for rest != "" && (rest[0] == 'i' || rest[0] == 's') {
+		flags += rest[:1]
+		rest = rest[1:]
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errUnsupported
+	}
+
+	m := &Match{Service: service, Soft: soft, Re: re}
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key := rest[:1]
+		if strings.HasPrefix(rest, "cpe:") {
+			key = "cpe:"
+		}
+		var value string
+		value, rest, err = delimited(rest[len(key):])
+		if err != nil {
+			return nil, fmt.Errorf("invalid match %q: %v", args, err)
+		}
+		switch key {
+		case "p":
+			m.Product = value
+		case "v":
+			m.Version = value
+		case "i":
+			m.Info = value
+		case "h":
+			m.Hostname = value
+		case "o":
+			m.OS = value
+		case "d":
+			m.Device = value
+		case "cpe:":
+			// skip the "a" flag of cpe entries
+			rest = strings.TrimPrefix(rest, "a")
+		}
+	}
+	return m, nil
+}
+
+// delimited returns the text up to the delimiter s starts with and the rest
+// after the closing delimiter
+func delimited(s string) (value, rest string, err error) {
+	if s == "" {
+		return "", "", fmt.Errorf("missing delimiter")
+	}
+	i := strings.IndexByte(s[1:], s[0])
+	if i < 0 {
+		return "", "", fmt.Errorf("missing closing %q", s[0])
+	}
+	return s[1 : i+1], s[i+2:], nil
+}
+
+// unescape interprets \r, \n, \t, \0 and \xNN escapes of probe payloads
+func unescape(s string) ([]byte, error) {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b = append(b, s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'r':
+			b = append(b, '\r')
+		case 'n':
+			b = append(b, '\n')
+		case 't':
+			b = append(b, '\t')
+		case '0':
+			b = append(b, 0)
+		case 'x':
+			if i+2 >= len(s) {
+				return nil, fmt.Errorf("short \\x escape")
+			}
+			v, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("bad \\x escape %q", s[i-1:i+3])
+			}
+			b = append(b, byte(v))
+			i += 2
+		default:
+			b = append(b, s[i])
+		}
+	}
+	return b, nil
+}
+
+// parsePorts parses "80,443,8000-8100" into ports
+func parsePorts(s string, ports map[int]bool) error {
+	for _, item := range strings.Split(s, ",") {
+		start, end, found := strings.Cut(strings.TrimSpace(item), "-")
+		if !found {
+			end = start
+		}
+		first, err := strconv.Atoi(start)
+		if err != nil {
+			return fmt.Errorf("invalid ports %q", s)
+		}
+		last, err := strconv.Atoi(end)
+		if err != nil || last < first {
+			return fmt.Errorf("invalid ports %q", s)
+		}
+		for port := first; port <= last; port++ {
+			ports[port] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"netscan/scan"
+)
+
+// Report is the document written by the JSON format
+type Report struct {
+	*Meta
+	End     time.Time `json:"end"`
+	Elapsed float64   `json:"elapsed"`
+	Hosts   []*Host   `json:"hosts"`
+}
+
+// Host and its reported ports
+type Host struct {
+	Host string `json:"host"`
+	IP   string `json:"ip"`
+	// Status and Reason of host discovery, if done
+	Status string `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	// Names found by reverse DNS
+	Names []string `json:"names,omitempty"`
+	// Ports is an empty array, not null, for hosts without reported ports
+	Ports []Port `json:"ports"`
+}
+
+type jsonWriter struct {
+	w      io.Writer
+	hosts  []*Host
+	byHost map[string]*Host
+}
+
+// NewJSON returns a Writer collecting all results into a single Report which
+// is written at the end of the scan
+func NewJSON(w io.Writer) Writer {
+	return &jsonWriter{w: w, hosts: []*Host{}, byHost: make(map[string]*Host)}
+}
+
+func (j *jsonWriter) Begin(*Meta) error {
+	return nil
+}
+
+// host returns the Host entry of host, added on first use
+func (j *jsonWriter) host(host string, addr net.IP) *Host {
+	h := j.byHost[hostKey(host, addr)]
+	if h == nil {
+		h = &Host{Host: host, IP: ip(host, addr), Ports: []Port{}}
+		j.byHost[hostKey(host, addr)] = h
+		j.hosts = append(j.hosts, h)
+	}
+	return h
+}
+
+func (j *jsonWriter) Host(st scan.HostStatus) error {
+	h := j.host(st.Host, st.IP)
+	h.Status, h.Reason = status(st), st.Reason
+	return nil
+}
+
+func (j *jsonWriter) Write(r scan.Result) error {
+	h := j.host(r.Host, r.IP)
+	if h.Names == nil {
+		h.Names = r.Names
+	}
+	h.Ports = append(h.Ports, newPort(r))
+	return nil
+}
+
+func (j *jsonWriter) End(m *Meta) error {
+	for _, h := range j.hosts {
+		sort.Slice(h.Ports, func(a, b int) bool { return h.Ports[a].Port < h.Ports[b].Port })
+	}
+	return WriteReport(j.w, &Report{
+		Meta:    m,
+		End:     m.End,
+		Elapsed: m.End.Sub(m.Start).Seconds(),
+		Hosts:   j.hosts,
+	})
+}
+
+// WriteReport writes r in the JSON format
+func WriteReport(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReport reads a Report written in the JSON format
+func ReadReport(r io.Reader) (*Report, error) {
+	report := &Report{}
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	if report.Meta == nil {
+		return nil, fmt.Errorf("not a netscan JSON report")
+	}
+	return report, nil
+}
+
+// Merge combines the Reports of the shards of a scan into one. The reports
+// must be of the same targets, ports, protocol and seed, and together hold
+// every shard once. A report without shard is the only shard 1/1.
+func Merge(reports ...*Report) (*Report, error) {
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no reports to merge")
+	}
+	if reports[0].Meta == nil {
+		return nil, fmt.Errorf("not a netscan JSON report")
+	}
+	meta := *reports[0].Meta
+	meta.Shard = ""
+	merged := &Report{Meta: &meta, End: reports[0].End, Hosts: []*Host{}}
+	byHost := make(map[string]*Host)
+	type portKey struct {
+		host     *Host
+		port     int
+		protocol string
+	}
+	seen := make(map[portKey]bool)
+	shards, total := make(map[int]bool), 0
+	for _, r := range reports {
+		if r.Meta == nil || r.Targets != meta.Targets || r.Ports != meta.Ports || r.Protocol != meta.Protocol || r.Seed != meta.Seed {
+			return nil, fmt.Errorf("can not merge reports of different scans")
+		}
+		i, n, err := parseShard(r.Shard)
+		if err != nil {
+			return nil, err
+		}
+		if total != 0 && n != total {
+			return nil, fmt.Errorf("can not merge shards of %d and %d", total, n)
+		}
+		if shards[i] {
+			return nil, fmt.Errorf("shard %d/%d is merged twice", i, n)
+		}
+		shards[i], total = true, n
+
+		if r.Start.Before(merged.Start) {
+			merged.Start = r.Start
+		}
+		if r.End.After(merged.End) {
+			merged.End = r.End
+		}
+		for _, h := range r.Hosts {
+			m := byHost[h.Host+" "+h.IP]
+			if m == nil {
+				m = &Host{Host: h.Host, IP: h.IP, Ports: []Port{}}
+				byHost[h.Host+" "+h.IP] = m
+				merged.Hosts = append(merged.Hosts, m)
+			}
+			if m.Status != "up" {
+				m.Status, m.Reason = h.Status, h.Reason
+			}
+			if m.Names == nil {
+				m.Names = h.Names
+			}
+			for _, p := range h.Ports {
+				if key := (portKey{m, p.Port, p.Protocol}); !seen[key] {
+					seen[key] = true
+					m.Ports = append(m.Ports, p)
+				}
+			}
+		}
+	}
+	var missing []string
+	for i := 1; i <= total; i++ {
+		if !shards[i] {
+			missing = append(missing, strconv.Itoa(i))
+		}
+	}
+	if missing != nil {
+		return nil, fmt.Errorf("missing shard %s of %d", strings.Join(missing, ", "), total)
+	}
+	for _, h := range merged.Hosts {
+		sort.Slice(h.Ports, func(a, b int) bool { return h.Ports[a].Port < h.Ports[b].Port })
+	}
+	merged.Elapsed = merged.End.Sub(merged.Start).Seconds()
+	return merged, nil
+}
+
+// parseShard parses the "i/n" shard of a report, 1/1 if empty
+func parseShard(shard string) (i, n int, err error) {
+	if shard == "" {
+		return 1, 1, nil
+	}
+	si, sn, ok := strings.Cut(shard, "/")
+	i, err1 := strconv.Atoi(si)
+	n, err2 := strconv.Atoi(sn)
+	if !ok || err1 != nil || err2 != nil || i < 1 || i > n {
+		return 0, 0, fmt.Errorf("invalid shard %q", shard)
+	}
+	return i, n, nil
+}
+
+// Record types of the NDJSON format
+const (
+	RecordBegin = "begin"
+	RecordHost  = "host"
+	RecordPort  = "port"
+	RecordEnd   = "end"
+)
+
+// Record is a single line of the NDJSON format, host records hold the
+// discovery status and port records the fields of Port
+type Record struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Meta   *Meta     `json:"meta,omitempty"`
+	Host   string    `json:"host,omitempty"`
+	IP     string    `json:"ip,omitempty"`
+	Status string    `json:"status,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Names  []string  `json:"names,omitempty"`
+	*Port
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+// NewNDJSON returns a Writer streaming one JSON object per line: a begin
+// record, a host record for every discovered host, a port record for every
+// result and an end record
+func NewNDJSON(w io.Writer) Writer {
+	return &ndjsonWriter{enc: json.NewEncoder(w)}
+}
+
+func (n *ndjsonWriter) Begin(m *Meta) error {
+	return n.enc.Encode(&Record{Type: RecordBegin, Time: m.Start, Meta: m})
+}
+
+func (n *ndjsonWriter) Host(st scan.HostStatus) error {
+	return n.enc.Encode(&Record{
+		Type:   RecordHost,
+		Time:   time.Now(),
+		Host:   st.Host,
+		IP:     ip(st.Host, st.IP),
+		Status: status(st),
+		Reason: st.Reason,
+	})
+}
+
+func (n *ndjsonWriter) Write(r scan.Result) error {
+	p := newPort(r)
+	return n.enc.Encode(&Record{
+		Type:  RecordPort,
+		Time:  time.Now(),
+		Host:  r.Host,
+		IP:    ip(r.Host, r.IP),
+		Names: r.Names,
+		Port:  &p,
+	})
+}
+
+func (n *ndjsonWriter) End(m *Meta) error {
+	return n.enc.Encode(&Record{Type: RecordEnd, Time: m.End, Meta: m})
+}
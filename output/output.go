@@ -0,0 +1,178 @@
+// Package output writes scan results as text, JSON, NDJSON or nmap XML.
+package output
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"netscan/detect"
+	"netscan/scan"
+)
+
+// Writer writes the results of a scan
+type Writer interface {
+	// Begin is called before the first result
+	Begin(m *Meta) error
+	// Host writes the discovery status of a host
+	Host(st scan.HostStatus) error
+	// Write a single result
+	Write(r scan.Result) error
+	// End is called after the last result, m.End is set
+	End(m *Meta) error
+}
+
+// Formats supported by New
+var Formats = []string{"text", "json", "ndjson", "xml"}
+
+// New returns a Writer for format writing to w
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "text", "":
+		return NewText(w), nil
+	case "json":
+		return NewJSON(w), nil
+	case "ndjson":
+		return NewNDJSON(w), nil
+	case "xml":
+		return NewXML(w), nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+type multiWriter []Writer
+
+// Multi returns a Writer duplicating its calls to all writers
+func Multi(writers ...Writer) Writer {
+	return multiWriter(writers)
+}
+
+func (mw multiWriter) Begin(m *Meta) error {
+	for _, w := range mw {
+		if err := w.Begin(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw multiWriter) Host(st scan.HostStatus) error {
+	for _, w := range mw {
+		if err := w.Host(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw multiWriter) Write(r scan.Result) error {
+	for _, w := range mw {
+		if err := w.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mw multiWriter) End(m *Meta) error {
+	for _, w := range mw {
+		if err := w.End(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Meta describes a scan and the parameters used
+type Meta struct {
+	Scanner   string    `json:"scanner"`
+	Args      []string  `json:"args"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"-"`
+	Targets   string    `json:"targets"`
+	Protocol  string    `json:"protocol"`
+	Ports     string    `json:"ports"`
+	Threads   int       `json:"threads"`
+	Timeout   string    `json:"timeout"`
+	ReportAll bool      `json:"all"`
+	// Seed of the random order, 0 when scanned in order
+	Seed int64 `json:"seed,omitempty"`
+	// Shard of the scan as "i/n", empty if not sharded
+	Shard string `json:"shard,omitempty"`
+}
+
+// NewMeta returns the Meta of a scan of targets with opts started now
+func NewMeta(args []string, targets string, opts scan.Options) *Meta {
+	m := &Meta{
+		Scanner:   "netscan",
+		Args:      args,
+		Start:     time.Now(),
+		Targets:   targets,
+		Protocol:  "tcp",
+		Ports:     scan.FormatPorts(opts.Ports),
+		Threads:   opts.Threads,
+		Timeout:   opts.Timeout.String(),
+		ReportAll: opts.ReportAll,
+	}
+	if opts.UDP {
+		m.Protocol = "udp"
+	}
+	if opts.Randomize {
+		m.Seed = opts.Seed
+	}
+	if opts.Shards > 1 {
+		m.Shard = fmt.Sprintf("%d/%d", opts.Shard, opts.Shards)
+	}
+	return m
+}
+
+// Port is a single port of a host in the JSON formats
+type Port struct {
+	Port        int             `json:"port"`
+	Protocol    string          `json:"protocol"`
+	State       scan.State      `json:"state"`
+	Latency     float64         `json:"latency_ms"`
+	Description string          `json:"description,omitempty"`
+	Banner      string          `json:"banner,omitempty"`
+	Service     *detect.Service `json:"service,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+func newPort(r scan.Result) Port {
+	p := Port{
+		Port:        r.Port,
+		Protocol:    r.Protocol,
+		State:       r.State,
+		Latency:     float64(r.Latency) / float64(time.Millisecond),
+		Description: r.Description,
+		Banner:      r.Banner,
+		Service:     r.Service,
+	}
+	if r.Err != nil {
+		p.Error = r.Err.Error()
+	}
+	return p
+}
+
+// ip returns the address of a host, or the host when it is not known
+func ip(host string, addr net.IP) string {
+	if addr == nil {
+		return host
+	}
+	return addr.String()
+}
+
+// hostKey identifies a host, names may be scanned at several addresses
+func hostKey(host string, addr net.IP) string {
+	return host + " " + ip(host, addr)
+}
+
+// status returns the state of a host, up or down
+func status(st scan.HostStatus) string {
+	if st.Up {
+		return "up"
+	}
+	return "down"
+}
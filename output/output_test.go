@@ -0,0 +1,264 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"netscan/detect"
+	"netscan/scan"
+)
+
+func testResults() []scan.Result {
+	ip := net.ParseIP("10.0.0.1")
+	return []scan.Result{
+		{Host: "10.0.0.1", IP: ip, Port: 513, Protocol: "tcp", State: scan.Open, Latency: time.Millisecond, Description: "login\nwho", Banner: "hello"},
+		{Host: "10.0.0.1", IP: ip, Port: 22, Protocol: "tcp", State: scan.Closed},
+		{Host: "10.0.0.1", IP: ip, Port: 2222, Protocol: "tcp", State: scan.Open, Service: &detect.Service{Name: "ssh", Product: "OpenSSH", Version: "9.6"}},
+	}
+}
+
+func write(t *testing.T, format string) string {
+	var buf bytes.Buffer
+	w, err := New(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := scan.DefaultOptions()
+	opts.Ports = []int{22, 513}
+	m := NewMeta([]string{"10.0.0.1"}, "10.0.0.1", opts)
+	if err := w.Begin(m); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range testResults() {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.End = m.Start.Add(time.Second)
+	if err := w.End(m); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestText(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(write(t, "text")), "\n")
+	if len(lines) != 6 || lines[4] != "          |_ service: ssh OpenSSH 9.6" || !strings.Contains(lines[0], "login; who") || !strings.HasSuffix(lines[1], "|_ hello") || lines[5] != "completed in 1s" {
+		t.Errorf("unexpected output %q", lines)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var report Report
+	if err := json.Unmarshal([]byte(write(t, "json")), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Ports != "22,513" || report.Protocol != "tcp" || report.Elapsed != 1 || len(report.Hosts) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	ports := report.Hosts[0].Ports
+	if len(ports) != 3 || ports[0].Port != 22 || ports[0].Protocol != "tcp" || ports[1].Latency != 1 || ports[1].Banner != "hello" || ports[2].Service.Version != "9.6" {
+		t.Errorf("unexpected ports %+v", ports)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	var types []string
+	for _, line := range strings.Split(strings.TrimSpace(write(t, "ndjson")), "\n") {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, rec["type"].(string))
+	}
+	if strings.Join(types, ",") != "begin,port,port,port,end" {
+		t.Errorf("unexpected records %v", types)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := New("yaml", nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestXML(t *testing.T) {
+	var run nmapRun
+	if err := xml.Unmarshal([]byte(write(t, "xml")), &run); err != nil {
+		t.Fatal(err)
+	}
+	if run.ScanInfo.NumServices != 2 || len(run.Hosts) != 1 {
+		t.Fatalf("unexpected run %+v", run)
+	}
+	ports := run.Hosts[0].Ports
+	if len(ports) != 3 || ports[2].Service.Product != "OpenSSH" || ports[0].PortID != 22 || ports[0].State.Reason != "conn-refused" || ports[0].Service.Name != "ssh" || ports[1].Scripts[0].Output != "hello" {
+		t.Errorf("unexpected ports %+v", ports)
+	}
+}
+
+func TestXMLUnreachable(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewXML(&buf)
+	m := NewMeta(nil, "example.com", scan.DefaultOptions())
+	w.Begin(m)
+	w.Write(scan.Result{Host: "example.com", Port: 80, Protocol: "tcp", State: scan.Unreachable})
+	w.Write(scan.Result{Host: "example.com", Port: 81, Protocol: "tcp", State: scan.Error})
+	m.End = time.Now()
+	if err := w.End(m); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "<address") {
+		t.Errorf("address of an unresolved name: %s", buf.String())
+	}
+	var run nmapRun
+	if err := xml.Unmarshal(buf.Bytes(), &run); err != nil {
+		t.Fatal(err)
+	}
+	ports := run.Hosts[0].Ports
+	if len(ports) != 1 || ports[0].State.State != "filtered" || ports[0].State.Reason != "host-unreach" {
+		t.Errorf("unexpected ports %+v", ports)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	var a, b bytes.Buffer
+	w := Multi(NewText(&a), NewText(&b))
+	if err := w.Write(testResults()[0]); err != nil {
+		t.Fatal(err)
+	}
+	if a.Len() == 0 || a.String() != b.String() {
+		t.Errorf("got %q and %q", a.String(), b.String())
+	}
+}
+
+func TestHost(t *testing.T) {
+	var text, js, x bytes.Buffer
+	w := Multi(NewText(&text), NewJSON(&js), NewXML(&x))
+	m := NewMeta(nil, "10.0.0.1-2", scan.DefaultOptions())
+	w.Begin(m)
+	w.Host(scan.HostStatus{Host: "10.0.0.1", IP: net.ParseIP("10.0.0.1"), Up: true, Reason: "echo-reply", Latency: time.Millisecond})
+	w.Host(scan.HostStatus{Host: "10.0.0.2", IP: net.ParseIP("10.0.0.2")})
+	m.End = time.Now()
+	if err := w.End(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(text.String(), "host 10.0.0.1 is up (echo-reply 1ms)\nhost 10.0.0.2 is down\n") {
+		t.Errorf("unexpected text %q", text.String())
+	}
+	if strings.Contains(js.String(), `"ports": null`) {
+		t.Errorf("hosts without ports: %s", js.String())
+	}
+	var report Report
+	if err := json.Unmarshal(js.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Hosts) != 2 || report.Hosts[0].Status != "up" || report.Hosts[0].Reason != "echo-reply" || report.Hosts[1].Status != "down" {
+		t.Errorf("unexpected hosts %+v", report.Hosts)
+	}
+	var run nmapRun
+	if err := xml.Unmarshal(x.Bytes(), &run); err != nil {
+		t.Fatal(err)
+	}
+	if s := run.RunStats.Hosts; s.Up != 1 || s.Down != 1 || run.Hosts[0].Status.Reason != "echo-reply" {
+		t.Errorf("unexpected run stats %+v", s)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	var reports []*Report
+	start := time.Now()
+	for i, r := range testResults() {
+		var buf bytes.Buffer
+		w := NewJSON(&buf)
+		opts := scan.DefaultOptions()
+		opts.Shard, opts.Shards = i+1, 3
+		m := NewMeta(nil, "10.0.0.1", opts)
+		m.Start = start.Add(time.Duration(i) * time.Second)
+		m.End = m.Start.Add(time.Second)
+		w.Write(r)
+		if err := w.End(m); err != nil {
+			t.Fatal(err)
+		}
+		report, err := ReadReport(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+
+	merged, err := Merge(reports...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Shard != "" || merged.Elapsed != 3 || len(merged.Hosts) != 1 || len(merged.Hosts[0].Ports) != 3 || merged.Hosts[0].Ports[0].Port != 22 {
+		t.Errorf("unexpected report %+v", merged)
+	}
+
+	// ports reported by two shards are merged once
+	reports[1].Hosts[0].Ports = append(reports[1].Hosts[0].Ports, reports[0].Hosts[0].Ports...)
+	if merged, err := Merge(reports...); err != nil || len(merged.Hosts[0].Ports) != 3 {
+		t.Errorf("duplicate ports: got %+v, %v", merged, err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		reports []*Report
+	}{
+		{"duplicate shard", []*Report{reports[0], reports[0], reports[1], reports[2]}},
+		{"missing shard", reports[:2]},
+		{"unsharded", []*Report{reports[0], {Meta: &Meta{Targets: "10.0.0.1", Protocol: "tcp", Ports: reports[0].Ports}}}},
+	} {
+		if _, err := Merge(test.reports...); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+
+	reports[1].Seed = 1
+	if _, err := Merge(reports...); err == nil {
+		t.Error("expected error merging different seeds")
+	}
+	reports[1].Seed = 0
+	reports[1].Ports = "1-1024"
+	if _, err := Merge(reports...); err == nil {
+		t.Error("expected error merging different scans")
+	}
+}
+
+func TestNames(t *testing.T) {
+	r := testResults()[0]
+	r.Names = []string{"gw.example.com"}
+	for format, want := range map[string]string{
+		"text":   "10.0.0.1 (gw.example.com)",
+		"json":   `"names": [`,
+		"ndjson": `"names":["gw.example.com"]`,
+		"xml":    `<hostname name="gw.example.com" type="PTR">`,
+	} {
+		var buf bytes.Buffer
+		w, _ := New(format, &buf)
+		m := NewMeta([]string{"10.0.0.1"}, "10.0.0.1", scan.DefaultOptions())
+		w.Begin(m)
+		w.Write(r)
+		w.End(m)
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: %q not in %s", format, want, buf.String())
+		}
+	}
+}
+
+func TestTextName(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewText(&buf)
+	w.Host(scan.HostStatus{Host: "example.com", IP: net.ParseIP("2001:db8::1"), Up: true, Reason: "syn-ack"})
+	w.Write(scan.Result{Host: "example.com", IP: net.ParseIP("2001:db8::1"), Port: 80, State: scan.Open, Names: []string{"www.example.com"}})
+	w.Write(scan.Result{Host: "fe80::1%eth0", IP: net.ParseIP("fe80::1"), Port: 80, State: scan.Open})
+	text := buf.String()
+	if !strings.Contains(text, "host example.com (2001:db8::1) is up") || !strings.Contains(text, " example.com (2001:db8::1) ") || strings.Contains(text, "www.example.com") || !strings.Contains(text, " fe80::1%eth0 ") {
+		t.Errorf("unexpected text %q", text)
+	}
+}
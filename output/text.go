@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"netscan/scan"
+)
+
+type textWriter struct {
+	w io.Writer
+}
+
+// NewText returns a Writer printing one line per result
+func NewText(w io.Writer) Writer {
+	return &textWriter{w: w}
+}
+
+func (t *textWriter) Begin(m *Meta) error {
+	if m.Seed == 0 {
+		return nil
+	}
+	// repeat the order with --seed
+	_, err := fmt.Fprintln(t.w, "random order seed", m.Seed)
+	return err
+}
+
+func (t *textWriter) Host(st scan.HostStatus) error {
+	if !st.Up {
+		_, err := fmt.Fprintf(t.w, "host %s is down\n", label(st.Host, st.IP))
+		return err
+	}
+	_, err := fmt.Fprintf(t.w, "host %s is up (%s %v)\n", label(st.Host, st.IP), st.Reason, st.Latency.Round(time.Microsecond))
+	return err
+}
+
+func (t *textWriter) Write(r scan.Result) error {
+	// some descriptions list several services on separate lines
+	desc := strings.ReplaceAll(r.Description, "\n", "; ")
+	addr := label(r.Host, r.IP)
+	if len(r.Names) > 0 && addr == r.Host {
+		addr += " (" + r.Names[0] + ")"
+	}
+	_, err := fmt.Fprintf(t.w, "%9d %10v %11s %45s\n", r.Port, addr, r.State, desc)
+	if err == nil && r.Service != nil {
+		_, err = fmt.Fprintf(t.w, "%9s |_ service: %s\n", "", r.Service)
+	}
+	if err == nil && r.Err != nil {
+		_, err = fmt.Fprintf(t.w, "%9s |_ error: %v\n", "", r.Err)
+	}
+	if err == nil && r.Banner != "" {
+		_, err = fmt.Fprintf(t.w, "%9s |_ %s\n", "", r.Banner)
+	}
+	return err
+}
+
+// label returns the address of a host, prefixed by its name if it was
+// scanned by name, as with several addresses of a name
+func label(host string, addr net.IP) string {
+	a, _, _ := strings.Cut(host, "%")
+	if addr == nil || net.ParseIP(a) != nil {
+		return host
+	}
+	return host + " (" + addr.String() + ")"
+}
+
+func (t *textWriter) End(m *Meta) error {
+	_, err := fmt.Fprintln(t.w, "completed in", m.End.Sub(m.Start).Round(time.Microsecond))
+	return err
+}
@@ -0,0 +1,297 @@
+package output
+
+import (
+	"encoding/xml"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"netscan/scan"
+)
+
+// nmap XML document, see https://nmap.org/book/nmap-dtd.html
+type nmapRun struct {
+	XMLName          xml.Name     `xml:"nmaprun"`
+	Scanner          string       `xml:"scanner,attr"`
+	Args             string       `xml:"args,attr"`
+	Start            int64        `xml:"start,attr"`
+	StartStr         string       `xml:"startstr,attr"`
+	Version          string       `xml:"version,attr"`
+	XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+	ScanInfo         nmapScanInfo `xml:"scaninfo"`
+	Hosts            []*nmapHost  `xml:"host"`
+	RunStats         nmapRunStats `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapHost struct {
+	StartTime int64         `xml:"starttime,attr"`
+	EndTime   int64         `xml:"endtime,attr"`
+	Status    nmapStatus    `xml:"status"`
+	Address   *nmapAddress  `xml:"address"`
+	Hostnames nmapHostnames `xml:"hostnames"`
+	Ports     []nmapPort    `xml:"ports>port"`
+	hasPTR    bool
+}
+
+type nmapStatus struct {
+	State  string `xml:"state,attr"`
+	Reason string `xml:"reason,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapState    `xml:"state"`
+	Service  *nmapService `xml:"service"`
+	Scripts  []nmapScript `xml:"script"`
+}
+
+type nmapState struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapService struct {
+	Name      string `xml:"name,attr"`
+	Product   string `xml:"product,attr,omitempty"`
+	Version   string `xml:"version,attr,omitempty"`
+	ExtraInfo string `xml:"extrainfo,attr,omitempty"`
+	Hostname  string `xml:"hostname,attr,omitempty"`
+	OSType    string `xml:"ostype,attr,omitempty"`
+	Device    string `xml:"devicetype,attr,omitempty"`
+	Method    string `xml:"method,attr"`
+	Conf      int    `xml:"conf,attr"`
+}
+
+type nmapScript struct {
+	ID     string `xml:"id,attr"`
+	Output string `xml:"output,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished `xml:"finished"`
+	Hosts    nmapHosts    `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time    int64   `xml:"time,attr"`
+	TimeStr string  `xml:"timestr,attr"`
+	Elapsed float64 `xml:"elapsed,attr"`
+	Summary string  `xml:"summary,attr"`
+	Exit    string  `xml:"exit,attr"`
+}
+
+type nmapHosts struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// reasons as reported by nmap connect and UDP scans
+var nmapReasons = map[string]map[scan.State]string{
+	"tcp": {
+		scan.Open:        "syn-ack",
+		scan.Closed:      "conn-refused",
+		scan.Filtered:    "no-response",
+		scan.Unreachable: "host-unreach",
+	},
+	"udp": {
+		scan.Open:         "udp-response",
+		scan.Closed:       "port-unreach",
+		scan.Filtered:     "admin-prohibited",
+		scan.Unreachable:  "host-unreach",
+		scan.OpenFiltered: "no-response",
+	},
+}
+
+// nmapPortState returns the nmap state of s. nmap has no unreachable state,
+// it reports those ports filtered with the reason host-unreach.
+func nmapPortState(s scan.State) string {
+	if s == scan.Unreachable {
+		return "filtered"
+	}
+	return s.String()
+}
+
+type xmlWriter struct {
+	w      io.Writer
+	hosts  []*nmapHost
+	byHost map[string]*nmapHost
+}
+
+// NewXML returns a Writer producing nmap compatible XML, written at the end
+// of the scan
+func NewXML(w io.Writer) Writer {
+	return &xmlWriter{w: w, byHost: make(map[string]*nmapHost)}
+}
+
+func (x *xmlWriter) Begin(*Meta) error {
+	return nil
+}
+
+// host returns the entry of host, added on first use. Hosts are up unless
+// discovery reported otherwise.
+func (x *xmlWriter) host(host string, addr net.IP) *nmapHost {
+	now := time.Now().Unix()
+	h := x.byHost[hostKey(host, addr)]
+	if h == nil {
+		h = &nmapHost{
+			StartTime: now,
+			Status:    nmapStatus{State: "up", Reason: "user-set"},
+		}
+		// unresolved names have no address
+		if addr != nil {
+			h.Address = &nmapAddress{Addr: addr.String(), AddrType: "ipv4"}
+			if addr.To4() == nil {
+				h.Address.AddrType = "ipv6"
+			}
+		}
+		if addr == nil || addr.String() != host {
+			h.Hostnames.Hostnames = append(h.Hostnames.Hostnames, nmapHostname{Name: host, Type: "user"})
+		}
+		x.byHost[hostKey(host, addr)] = h
+		x.hosts = append(x.hosts, h)
+	}
+	h.EndTime = now
+	return h
+}
+
+func (x *xmlWriter) Host(st scan.HostStatus) error {
+	h := x.host(st.Host, st.IP)
+	h.Status = nmapStatus{State: status(st), Reason: st.Reason}
+	if !st.Up {
+		h.Status.Reason = "no-response"
+	}
+	return nil
+}
+
+func (x *xmlWriter) Write(r scan.Result) error {
+	if r.State == scan.Error {
+		// nmap has no state for ports which were not probed
+		return nil
+	}
+	h := x.host(r.Host, r.IP)
+	if len(r.Names) > 0 && !h.hasPTR {
+		for _, name := range r.Names {
+			h.Hostnames.Hostnames = append(h.Hostnames.Hostnames, nmapHostname{Name: name, Type: "PTR"})
+		}
+		h.hasPTR = true
+	}
+
+	p := nmapPort{
+		Protocol: r.Protocol,
+		PortID:   r.Port,
+		State:    nmapState{State: nmapPortState(r.State), Reason: nmapReasons[r.Protocol][r.State]},
+	}
+	if svc := r.Service; svc != nil {
+		p.Service = &nmapService{
+			Name:      svc.Name,
+			Product:   svc.Product,
+			Version:   svc.Version,
+			ExtraInfo: svc.Info,
+			Hostname:  svc.Hostname,
+			OSType:    svc.OS,
+			Device:    svc.Device,
+			Method:    "probed",
+			Conf:      10,
+		}
+	} else if name := scan.ServiceName(r.Protocol, r.Port); name != "" {
+		p.Service = &nmapService{Name: name, Method: "table", Conf: 3}
+	}
+	if r.Banner != "" {
+		p.Scripts = append(p.Scripts, nmapScript{ID: "banner", Output: r.Banner})
+	}
+	h.Ports = append(h.Ports, p)
+	return nil
+}
+
+func (x *xmlWriter) End(m *Meta) error {
+	for _, h := range x.hosts {
+		sort.Slice(h.Ports, func(a, b int) bool { return h.Ports[a].PortID < h.Ports[b].PortID })
+	}
+	up := 0
+	for _, h := range x.hosts {
+		if h.Status.State == "up" {
+			up++
+		}
+	}
+	elapsed := m.End.Sub(m.Start)
+	run := &nmapRun{
+		Scanner:          m.Scanner,
+		Args:             strings.Join(append([]string{m.Scanner}, m.Args...), " "),
+		Start:            m.Start.Unix(),
+		StartStr:         m.Start.Format(time.ANSIC),
+		Version:          "1.0",
+		XMLOutputVersion: "1.05",
+		ScanInfo: nmapScanInfo{
+			Type:        scanType(m.Protocol),
+			Protocol:    m.Protocol,
+			NumServices: numPorts(m.Ports),
+			Services:    m.Ports,
+		},
+		Hosts: x.hosts,
+		RunStats: nmapRunStats{
+			Finished: nmapFinished{
+				Time:    m.End.Unix(),
+				TimeStr: m.End.Format(time.ANSIC),
+				Elapsed: elapsed.Seconds(),
+				Summary: "netscan done at " + m.End.Format(time.ANSIC) + "; " + strconv.Itoa(up) + " hosts up scanned in " + elapsed.Round(time.Millisecond).String(),
+				Exit:    "success",
+			},
+			Hosts: nmapHosts{Up: up, Down: len(x.hosts) - up, Total: len(x.hosts)},
+		},
+	}
+
+	if _, err := io.WriteString(x.w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(x.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+// scanType returns the nmap scan type of protocol
+func scanType(protocol string) string {
+	if protocol == "udp" {
+		return "udp"
+	}
+	return "connect"
+}
+
+// numPorts counts the ports of a FormatPorts list
+func numPorts(ports string) int {
+	list, err := scan.ParsePorts(ports)
+	if err != nil {
+		return 0
+	}
+	return len(list)
+}
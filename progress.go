@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"netscan/scan"
+)
+
+// progress reports the statistics of the running phase on stderr. On a
+// terminal a status line is updated in place, otherwise a line is logged
+// every interval. The status is always logged on SIGUSR1.
+type progress struct {
+	stats    *scan.Stats
+	interval time.Duration
+	tty      bool
+	log      *log.Logger
+	// random order only counts the hosts done at the end
+	random bool
+
+	mu    sync.Mutex
+	phase string
+	start time.Time
+	// base is the position the phase started at
+	base uint64
+	// shown is set while the status line is on the terminal
+	shown bool
+	done  chan struct{}
+}
+
+// newProgress reports stats every interval, 0 for the default of the
+// terminal, and only on SIGUSR1 unless enabled. random leaves out the hosts
+// done of the scan.
+func newProgress(stats *scan.Stats, interval time.Duration, enabled, random bool) *progress {
+	p := &progress{
+		stats:  stats,
+		log:    log.New(os.Stderr, "", log.LstdFlags),
+		random: random,
+		done:   make(chan struct{}),
+	}
+	if fi, err := os.Stderr.Stat(); err == nil {
+		p.tty = fi.Mode()&os.ModeCharDevice != 0
+	}
+	if interval == 0 {
+		interval = 30 * time.Second
+		if p.tty {
+			interval = time.Second
+		}
+	}
+	if enabled {
+		p.interval = interval
+	}
+	go p.run()
+	return p
+}
+
+func (p *progress) run() {
+	status := make(chan os.Signal, 1)
+	notifyStatus(status)
+	var tick <-chan time.Time
+	if p.interval > 0 {
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-tick:
+			p.mu.Lock()
+			if p.tty {
+				p.draw()
+			} else if p.phase != "" {
+				p.log.Print(p.line())
+			}
+			p.mu.Unlock()
+		case <-status:
+			p.mu.Lock()
+			p.clear()
+			p.log.Print(p.line())
+			p.mu.Unlock()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// begin a phase, after Scan or Discover started
+func (p *progress) begin(phase string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.phase, p.start, p.base = phase, time.Now(), p.stats.Position.Load()
+}
+
+// stop reporting and remove the status line
+func (p *progress) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	close(p.done)
+	p.clear()
+	p.phase = ""
+}
+
+// line returns the status of the phase
+func (p *progress) line() string {
+	if p.phase == "" {
+		return "starting"
+	}
+	s := p.stats
+	pos, total := s.Position.Load(), s.Total.Load()
+	if pos > total {
+		pos = total
+	}
+	elapsed := time.Since(p.start)
+	percent := 100.0
+	if total > 0 {
+		percent = float64(pos) * 100 / float64(total)
+	}
+	rate := float64(s.Sent.Load()) / elapsed.Seconds()
+	eta := "-"
+	if pos > p.base {
+		left := time.Duration(float64(elapsed) * float64(total-pos) / float64(pos-p.base))
+		eta = left.Round(time.Second).String()
+	}
+	if p.phase == phaseDiscovery {
+		return fmt.Sprintf("discovery %.1f%%, %d/%d hosts, %.0f/s, %d up, ETA %s",
+			percent, s.HostsDone.Load(), s.Hosts.Load(), rate, s.Found.Load(), eta)
+	}
+	if p.random {
+		return fmt.Sprintf("scan %.1f%%, %d/%d probes, %.0f/s, %d open, ETA %s",
+			percent, pos, total, rate, s.Found.Load(), eta)
+	}
+	return fmt.Sprintf("scan %.1f%%, %d/%d probes, %.0f/s, %d open, %d/%d hosts done, ETA %s",
+		percent, pos, total, rate, s.Found.Load(), s.HostsDone.Load(), s.Hosts.Load(), eta)
+}
+
+func (p *progress) draw() {
+	if p.phase != "" {
+		fmt.Fprint(os.Stderr, "\r\033[K"+p.line())
+		p.shown = true
+	}
+}
+
+func (p *progress) clear() {
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// writer clears the status line before writes to w, which share the
+// terminal. It is redrawn on the next update.
+func (p *progress) writer(w io.Writer) io.Writer {
+	if !p.tty {
+		return w
+	}
+	return progressWriter{p, w}
+}
+
+type progressWriter struct {
+	p *progress
+	w io.Writer
+}
+
+func (pw progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.p.clear()
+	return pw.w.Write(b)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"netscan/target"
+)
+
+// scan phases recorded in the resume state
+const (
+	phaseDiscovery = "discovery"
+	phaseScan      = "scan"
+)
+
+// state of an interrupted scan, written on interrupt and read by --resume
+type state struct {
+	// Args are the arguments of the scan, parsed again on resume
+	Args    []string `json:"args"`
+	Targets string   `json:"targets"`
+	// Hosts is the target.List Fingerprint of the parsed targets and Ports
+	// the ports, which must be the same on resume
+	Hosts string `json:"hosts"`
+	Ports string `json:"ports"`
+	Seed  int64  `json:"seed,omitempty"`
+	// Phase is discovery or scan, Position the position of the phase in
+	// the probe order
+	Phase    string `json:"phase"`
+	Position uint64 `json:"position"`
+	// Live hosts found by discovery
+	Live target.Hosts `json:"live,omitempty"`
+}
+
+// loadState reads the state file path
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &state{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(s.Args) == 0 || (s.Phase != phaseDiscovery && s.Phase != phaseScan) {
+		return nil, fmt.Errorf("%s: not a netscan resume file", path)
+	}
+	return s, nil
+}
+
+// save the state to path
+func (s *state) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
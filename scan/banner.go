@@ -0,0 +1,103 @@
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// grabBanner reads the greeting the service on conn volunteers. When it stays
+// silent and opts.Banner is set opts.BannerProbe is sent and the reply is
+// returned as banner instead. With opts.Detect the greeting is awaited as
+// long as its NULL probe says.
+func grabBanner(conn net.Conn, opts *Options) (greeting, banner []byte) {
+	wait := opts.BannerTimeout
+	if opts.Detect != nil {
+		wait = opts.Detect.GreetingWait(wait)
+	}
+	buf := make([]byte, opts.BannerSize)
+	greeting = buf[:readBanner(conn, buf, wait)]
+	if len(greeting) > 0 || !opts.Banner || len(opts.BannerProbe) == 0 {
+		return greeting, greeting
+	}
+	conn.SetWriteDeadline(time.Now().Add(opts.BannerTimeout))
+	if _, err := conn.Write(opts.BannerProbe); err != nil {
+		return greeting, nil
+	}
+	buf = make([]byte, opts.BannerSize)
+	return greeting, buf[:readBanner(conn, buf, opts.BannerTimeout)]
+}
+
+// readBanner fills buf until it is full, the connection is closed or the
+// service stops sending for timeout
+func readBanner(conn net.Conn, buf []byte, timeout time.Duration) int {
+	n := 0
+	for n < len(buf) {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil || m == 0 {
+			break
+		}
+		// after the first chunk only wait briefly for more
+		if timeout > 100*time.Millisecond {
+			timeout = 100 * time.Millisecond
+		}
+	}
+	return n
+}
+
+// Sanitize returns b as printable single line text. Line breaks and tabs are
+// written as \r, \n and \t, other control and non ASCII bytes as \xNN.
+func Sanitize(b []byte) string {
+	b = bytes.TrimRight(b, " \t\r\n\x00")
+	var s strings.Builder
+	for _, c := range b {
+		switch {
+		case c == '\r':
+			s.WriteString(`\r`)
+		case c == '\n':
+			s.WriteString(`\n`)
+		case c == '\t':
+			s.WriteString(`\t`)
+		case c == '\\':
+			s.WriteString(`\\`)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&s, `\x%02x`, c)
+		default:
+			s.WriteByte(c)
+		}
+	}
+	return s.String()
+}
+
+// BannerProbes are the named generic probes accepted by ParseBannerProbe
+var BannerProbes = map[string]string{
+	"crlf": "\r\n",
+	"http": "HEAD / HTTP/1.0\r\n\r\n",
+}
+
+// ParseBannerProbe returns the named probe of BannerProbes, or s with Go
+// escape sequences like \r\n interpreted
+func ParseBannerProbe(s string) ([]byte, error) {
+	if p, ok := BannerProbes[s]; ok {
+		return []byte(p), nil
+	}
+	var b bytes.Buffer
+	for tail := s; tail != ""; {
+		c, multibyte, t, err := strconv.UnquoteChar(tail, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid banner probe %q: %v", s, err)
+		}
+		if multibyte {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte(byte(c))
+		}
+		tail = t
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// serve accepts connections on a local port and hands them to handle
+func serve(t *testing.T, handle func(net.Conn)) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				handle(conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func bannerOf(t *testing.T, port int, probe []byte) string {
+	opts := DefaultOptions()
+	opts.Ports = []int{port}
+	opts.Banner = true
+	opts.BannerTimeout = 200 * time.Millisecond
+	opts.BannerProbe = probe
+
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		return r.Banner
+	}
+	t.Fatal("port not open")
+	return ""
+}
+
+func TestBanner(t *testing.T) {
+	port := serve(t, func(conn net.Conn) {
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		time.Sleep(100 * time.Millisecond)
+	})
+	if b := bannerOf(t, port, nil); b != "SSH-2.0-OpenSSH_9.6" {
+		t.Errorf("got banner %q", b)
+	}
+}
+
+func TestBannerProbe(t *testing.T) {
+	port := serve(t, func(conn net.Conn) {
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if line == "HEAD / HTTP/1.0\r\n" {
+			conn.Write([]byte("HTTP/1.0 200 OK\r\nServer: test\r\n\r\n"))
+		}
+	})
+	if b := bannerOf(t, port, nil); b != "" {
+		t.Errorf("got banner %q without probe", b)
+	}
+	probe, _ := ParseBannerProbe("http")
+	if b := bannerOf(t, port, probe); b != `HTTP/1.0 200 OK\r\nServer: test` {
+		t.Errorf("got banner %q", b)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	if s := Sanitize([]byte("a\tb\\c\x00\xff\r\n\x00")); s != `a\tb\\c\x00\xff` {
+		t.Errorf("got %q", s)
+	}
+}
+
+func TestParseBannerProbe(t *testing.T) {
+	for s, want := range map[string]string{"crlf": "\r\n", `GET /\r\n\x00`: "GET /\r\n\x00", "plain": "plain"} {
+		if p, err := ParseBannerProbe(s); err != nil || string(p) != want {
+			t.Errorf("%q: got %q, %v", s, p, err)
+		}
+	}
+	if _, err := ParseBannerProbe(`bad\`); err == nil {
+		t.Error("expected error")
+	}
+}
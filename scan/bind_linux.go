@@ -0,0 +1,19 @@
+//go:build linux
+
+package scan
+
+import "syscall"
+
+// canBindToDevice tells if sockets can be bound to an interface
+const canBindToDevice = true
+
+// bindToDevice binds sockets to the interface name with SO_BINDTODEVICE
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) { err = syscall.BindToDevice(int(fd), name) }); cerr != nil {
+			return cerr
+		}
+		return err
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package scan
+
+import "syscall"
+
+// canBindToDevice tells if sockets can be bound to an interface
+const canBindToDevice = false
+
+// bindToDevice is only implemented with SO_BINDTODEVICE of Linux
+func bindToDevice(name string) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
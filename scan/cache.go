@@ -0,0 +1,44 @@
+package scan
+
+import "container/list"
+
+// maxScanners bounds the hosts random order remembers the Scanners of
+const maxScanners = 1 << 16
+
+// scannerCache keeps the Scanners of the most recently probed hosts, so
+// random order reuses them and their round trip times. The least recently
+// used is dropped beyond max.
+type scannerCache struct {
+	max   int
+	order *list.List // of cached, most recent first
+	hosts map[uint64]*list.Element
+}
+
+type cached struct {
+	host uint64
+	scan *Scanner
+}
+
+func newScannerCache(max int) *scannerCache {
+	return &scannerCache{max: max, order: list.New(), hosts: make(map[uint64]*list.Element)}
+}
+
+// get returns the Scanner of host, nil if it is not cached
+func (c *scannerCache) get(host uint64) *Scanner {
+	e := c.hosts[host]
+	if e == nil {
+		return nil
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(cached).scan
+}
+
+// add the Scanner of host
+func (c *scannerCache) add(host uint64, scan *Scanner) {
+	c.hosts[host] = c.order.PushFront(cached{host: host, scan: scan})
+	if c.order.Len() > c.max {
+		e := c.order.Back()
+		c.order.Remove(e)
+		delete(c.hosts, e.Value.(cached).host)
+	}
+}
@@ -0,0 +1,21 @@
+package scan
+
+import "testing"
+
+func TestScannerCache(t *testing.T) {
+	c := newScannerCache(2)
+	a, b, d := &Scanner{}, &Scanner{}, &Scanner{}
+	c.add(1, a)
+	c.add(2, b)
+	if c.get(1) != a {
+		t.Error("host 1 not cached")
+	}
+	// host 2 is the least recently used
+	c.add(3, d)
+	if c.get(2) != nil || c.get(1) != a || c.get(3) != d {
+		t.Error("wrong host dropped")
+	}
+	if len(c.hosts) != 2 || c.order.Len() != 2 {
+		t.Errorf("%d hosts cached", len(c.hosts))
+	}
+}
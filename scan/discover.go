@@ -0,0 +1,153 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HostStatus is the result of host discovery
+type HostStatus struct {
+	Host string
+	IP   net.IP
+	Up   bool
+	// Reason the host is up: echo-reply, syn-ack or conn-refused
+	Reason  string
+	Latency time.Duration
+	// Port is the only port to scan on the host, see target.Host
+	Port int
+}
+
+// errPingUnsupported is returned by ping where ICMP echo is not available
+var errPingUnsupported = errors.New("ping not supported")
+
+// Discover finds the hosts that are up and sends the status of every host
+// over the returned channel. A host is up when it answers an ICMP echo
+// request, sent over an unprivileged ping socket where the system permits,
+// or accepts or refuses a connection to one of opts.DiscoveryPorts. No echo
+// requests are sent with Options.Proxies.
+// Cancellation and Options.Resume work as with Scan.
+func Discover(ctx context.Context, hosts Targets, opts Options) <-chan HostStatus {
+	if opts.Threads < 1 {
+		opts.Threads = 1
+	}
+	status := make(chan HostStatus)
+	jobs := make(chan job)
+
+	wg := workers(opts.Threads, jobs, func(j job) {
+		st := j.h.discover(context.Background())
+		if st.Up {
+			opts.Stats.found()
+		}
+		opts.Stats.hostDone()
+		status <- st
+	})
+	seq := opts.newSequence(hosts.Len())
+	var done uint64
+	if !opts.Randomize {
+		done = seq.Pos()
+	}
+	opts.Stats.start(seq.Len(), hosts.Len(), done, seq.Pos())
+	go func() {
+		wg.Wait()
+		close(status)
+	}()
+
+	go func() {
+		defer close(jobs)
+		limiter := opts.newLimiter()
+		src := newSources(&opts)
+		for {
+			i, ok := seq.Next()
+			if !ok {
+				return
+			}
+			scan := newScanner(hosts.Host(i), &opts, src)
+			// the limiter applies to each probe of discover
+			scan.limiter = limiter
+			if !dispatch(ctx, nil, jobs, job{h: scan}) {
+				return
+			}
+			opts.Stats.sent(seq.Pos())
+		}
+	}()
+
+	return status
+}
+
+// discover probes the host with an echo request and connections to the
+// discovery ports at once, the first answer decides
+func (h *Scanner) discover(ctx context.Context) HostStatus {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	answers := make(chan HostStatus, len(h.opts.DiscoveryPorts)+1)
+	wg := &sync.WaitGroup{}
+	probe := func(f func(st *HostStatus) bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if h.limiter != nil && h.limiter.Wait(ctx) != nil {
+				return
+			}
+			st := HostStatus{Host: h.host, IP: h.ip, Up: true, Port: h.port}
+			if f(&st) {
+				answers <- st
+			}
+		}()
+	}
+
+	if h.ip != nil && len(h.opts.Proxies) == 0 {
+		probe(func(st *HostStatus) bool {
+			var err error
+			st.Latency, err = ping(ctx, h.addr, h.ip, h.src, h.timeout())
+			st.Reason = "echo-reply"
+			return err == nil
+		})
+	}
+	for _, port := range h.opts.DiscoveryPorts {
+		addr := net.JoinHostPort(h.addr, strconv.Itoa(port))
+		probe(func(st *HostStatus) bool {
+			t := time.Now()
+			conn, err := h.dial(ctx, "tcp", addr)
+			st.Latency = time.Since(t)
+			switch classify(err) {
+			case Open:
+				conn.Close()
+				st.Reason = "syn-ack"
+			case Closed:
+				st.Reason = "conn-refused"
+			default:
+				return false
+			}
+			return true
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(answers)
+	}()
+
+	if st, ok := <-answers; ok {
+		return st
+	}
+	return HostStatus{Host: h.host, IP: h.ip, Port: h.port}
+}
+
+// checksum is the internet checksum of RFC 1071
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
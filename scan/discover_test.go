@@ -0,0 +1,42 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDiscover(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.DiscoveryPorts = []int{port}
+	opts.Timeout = time.Second
+
+	var found []HostStatus
+	for st := range Discover(context.Background(), HostList{"127.0.0.1"}, opts) {
+		found = append(found, st)
+	}
+	if len(found) != 1 || !found[0].Up || found[0].Host != "127.0.0.1" {
+		t.Fatalf("expected 127.0.0.1 up, got %+v", found)
+	}
+	if r := found[0].Reason; r != "syn-ack" && r != "echo-reply" {
+		t.Errorf("unexpected reason %q", r)
+	}
+}
+
+func TestPing(t *testing.T) {
+	_, err := ping(context.Background(), "127.0.0.1", net.ParseIP("127.0.0.1"), &sources{}, time.Second)
+	if err != nil {
+		t.Skip("ping sockets not permitted:", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// echo request with identifier 1 and sequence 1
+	msg := []byte{8, 0, 0, 0, 0, 1, 0, 1}
+	if sum := checksum(msg); sum != 0xf7fd {
+		t.Errorf("checksum = %#04x, want 0xf7fd", sum)
+	}
+}
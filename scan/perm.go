@@ -0,0 +1,212 @@
+package scan
+
+import (
+	"math/big"
+	"math/bits"
+	"math/rand"
+)
+
+// Permutation walks the integers 0 to n-1 in a pseudo random order without
+// storing them. It repeatedly multiplies by a generator of the
+// multiplicative group modulo the smallest prime p > n, which cycles through
+// every number from 1 to p-1, and skips the numbers outside the range.
+// The order only depends on n and the seed.
+type Permutation struct {
+	n, p, g    uint64
+	first, cur uint64
+	started    bool
+	steps      uint64
+}
+
+// NewPermutation of the integers 0 to n-1 for seed
+func NewPermutation(n uint64, seed int64) *Permutation {
+	rnd := rand.New(rand.NewSource(seed))
+	p := nextPrime(n + 1)
+	first := 1 + rnd.Uint64()%(p-1)
+	return &Permutation{
+		n:     n,
+		p:     p,
+		g:     generator(p, rnd),
+		first: first,
+		cur:   first,
+	}
+}
+
+// Next returns the next integer, false after all were returned
+func (pm *Permutation) Next() (uint64, bool) {
+	for !pm.started || pm.cur != pm.first {
+		v := pm.cur - 1
+		pm.cur = mulmod(pm.cur, pm.g, pm.p)
+		pm.started = true
+		pm.steps++
+		if v < pm.n {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Pos returns the number of steps taken, including skipped numbers
+func (pm *Permutation) Pos() uint64 {
+	return pm.steps
+}
+
+// Len returns the number of steps of the whole walk, p-1
+func (pm *Permutation) Len() uint64 {
+	return pm.p - 1
+}
+
+// Seek continues the walk after pos steps
+func (pm *Permutation) Seek(pos uint64) {
+	if pos > pm.p-1 {
+		pos = pm.p - 1
+	}
+	pm.cur = mulmod(pm.first, powmod(pm.g, pos, pm.p), pm.p)
+	pm.started = pos > 0
+	pm.steps = pos
+}
+
+// sequence of probe indices which can be continued from a position. Len is
+// the position after the last index.
+type sequence interface {
+	Next() (uint64, bool)
+	Pos() uint64
+	Len() uint64
+	Seek(pos uint64)
+}
+
+// newSequence returns the integers 0 to n-1 in order, or permuted with
+// opts.Randomize, starting at opts.Resume. With opts.Shards only the
+// integers of opts.Shard are returned.
+func (opts *Options) newSequence(n uint64) sequence {
+	var seq sequence = &counter{n: n}
+	if opts.Randomize {
+		seq = NewPermutation(n, opts.Seed)
+	}
+	if opts.Shards > 1 {
+		seq = &shard{seq: seq, i: uint64(opts.Shard - 1), n: uint64(opts.Shards)}
+	}
+	seq.Seek(opts.Resume)
+	return seq
+}
+
+// shard takes every n-th step of a sequence, starting at step i. The shards
+// 0 to n-1 of the same sequence return every integer exactly once.
+type shard struct {
+	seq  sequence
+	i, n uint64
+}
+
+func (s *shard) Next() (uint64, bool) {
+	for {
+		v, ok := s.seq.Next()
+		if !ok || (s.seq.Pos()-1)%s.n == s.i {
+			return v, ok
+		}
+	}
+}
+
+func (s *shard) Pos() uint64 {
+	return s.seq.Pos()
+}
+
+func (s *shard) Len() uint64 {
+	return s.seq.Len()
+}
+
+func (s *shard) Seek(pos uint64) {
+	s.seq.Seek(pos)
+}
+
+// counter is the sequence in order
+type counter struct {
+	i, n uint64
+}
+
+func (c *counter) Next() (uint64, bool) {
+	if c.i >= c.n {
+		return 0, false
+	}
+	c.i++
+	return c.i - 1, true
+}
+
+func (c *counter) Pos() uint64 {
+	return c.i
+}
+
+func (c *counter) Len() uint64 {
+	return c.n
+}
+
+func (c *counter) Seek(pos uint64) {
+	c.i = pos
+}
+
+// nextPrime returns the smallest prime >= n
+func nextPrime(n uint64) uint64 {
+	if n <= 2 {
+		return 2
+	}
+	for p := n | 1; ; p += 2 {
+		// exact below 2^64
+		if new(big.Int).SetUint64(p).ProbablyPrime(0) {
+			return p
+		}
+	}
+}
+
+// generator returns a random primitive root modulo the prime p
+func generator(p uint64, rnd *rand.Rand) uint64 {
+	if p == 2 {
+		return 1
+	}
+	factors := primeFactors(p - 1)
+	for {
+		g := 2 + rnd.Uint64()%(p-2)
+		primitive := true
+		for _, q := range factors {
+			if powmod(g, (p-1)/q, p) == 1 {
+				primitive = false
+				break
+			}
+		}
+		if primitive {
+			return g
+		}
+	}
+}
+
+// primeFactors returns the distinct prime factors of n
+func primeFactors(n uint64) []uint64 {
+	var factors []uint64
+	for d := uint64(2); d*d <= n; d++ {
+		if n%d != 0 {
+			continue
+		}
+		factors = append(factors, d)
+		for n%d == 0 {
+			n /= d
+		}
+	}
+	if n > 1 {
+		factors = append(factors, n)
+	}
+	return factors
+}
+
+func mulmod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func powmod(b, e, m uint64) uint64 {
+	r := uint64(1) % m
+	for b %= m; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mulmod(r, b, m)
+		}
+		b = mulmod(b, b, m)
+	}
+	return r
+}
@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	for _, n := range []uint64{0, 1, 2, 3, 10, 1000, 65536} {
+		seen := make([]bool, n)
+		count := uint64(0)
+		pm := NewPermutation(n, 42)
+		next := pm.Next
+		for v, ok := next(); ok; v, ok = next() {
+			if v >= n || seen[v] {
+				t.Fatalf("n=%d: unexpected %d", n, v)
+			}
+			seen[v] = true
+			count++
+		}
+		if count != n {
+			t.Errorf("n=%d: got %d values", n, count)
+		}
+		if pm.Pos() != pm.Len() {
+			t.Errorf("n=%d: walk ended at %d of %d", n, pm.Pos(), pm.Len())
+		}
+	}
+}
+
+func TestPermutationSeed(t *testing.T) {
+	walk := func(seed int64) []uint64 {
+		var list []uint64
+		next := NewPermutation(100, seed).Next
+		for v, ok := next(); ok; v, ok = next() {
+			list = append(list, v)
+		}
+		return list
+	}
+	a, b, c := walk(1), walk(1), walk(2)
+	same, sorted := true, true
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("seed 1 differs at %d: %d and %d", i, a[i], b[i])
+		}
+		same = same && a[i] == c[i]
+		sorted = sorted && a[i] == uint64(i)
+	}
+	if same || sorted {
+		t.Errorf("expected different orders, got %v and %v", a, c)
+	}
+}
+
+func TestScanRandomize(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.Ports = PortRange(port-20, port+20)
+	opts.Randomize = true
+	opts.Seed = 7
+	opts.ReportAll = true
+
+	seen := make(map[int]bool)
+	for r := range Scan(context.Background(), HostList{"127.0.0.1", "localhost"}, opts) {
+		if r.Host == "127.0.0.1" {
+			seen[r.Port] = true
+		}
+	}
+	if len(seen) != len(opts.Ports) {
+		t.Errorf("expected %d ports, got %d", len(opts.Ports), len(seen))
+	}
+}
+
+func TestPermutationSeek(t *testing.T) {
+	a := NewPermutation(1000, 3)
+	for i := 0; i < 500; i++ {
+		a.Next()
+	}
+	b := NewPermutation(1000, 3)
+	b.Seek(a.Pos())
+	for {
+		va, oka := a.Next()
+		vb, okb := b.Next()
+		if va != vb || oka != okb {
+			t.Fatalf("got %d and %d after seek", va, vb)
+		}
+		if !oka {
+			break
+		}
+	}
+}
+
+func TestScanResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := DefaultOptions()
+	opts.Ports = PortRange(1, 500)
+	opts.ReportAll = true
+	opts.Randomize = true
+	opts.Stats = &Stats{}
+
+	seen := make(map[int]int)
+	for r := range Scan(ctx, HostList{"127.0.0.1"}, opts) {
+		if seen[r.Port]++; len(seen) == 100 {
+			cancel()
+		}
+	}
+	opts.Resume = opts.Stats.Position.Load()
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		seen[r.Port]++
+	}
+	for port := 1; port <= 500; port++ {
+		if seen[port] != 1 {
+			t.Fatalf("port %d scanned %d times", port, seen[port])
+		}
+	}
+}
+
+func TestShards(t *testing.T) {
+	for _, randomize := range []bool{false, true} {
+		seen := make(map[uint64]int)
+		for i := 1; i <= 3; i++ {
+			opts := Options{Randomize: randomize, Seed: 5, Shard: i, Shards: 3}
+			seq := opts.newSequence(1000)
+			for v, ok := seq.Next(); ok; v, ok = seq.Next() {
+				seen[v]++
+			}
+		}
+		for v := uint64(0); v < 1000; v++ {
+			if seen[v] != 1 {
+				t.Fatalf("randomize %v: %d returned %d times", randomize, v, seen[v])
+			}
+		}
+	}
+}
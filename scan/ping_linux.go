@@ -0,0 +1,95 @@
+//go:build linux
+
+package scan
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ping sends an ICMP echo request to ip over an unprivileged ping socket and
+// waits for the reply. Ping sockets are permitted for the groups in
+// net.ipv4.ping_group_range, otherwise the socket call fails. The socket is
+// bound like the connections of src.
+func ping(ctx context.Context, host string, ip net.IP, src *sources, timeout time.Duration) (time.Duration, error) {
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	request, reply := byte(8), byte(0)
+	if ip.To4() == nil {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		request, reply = 128, 129
+	}
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return 0, err
+	}
+	if err := bindPing(fd, ip, src); err != nil {
+		syscall.Close(fd)
+		return 0, err
+	}
+	f := os.NewFile(uintptr(fd), "ping")
+	conn, err := net.FilePacketConn(f)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	// the kernel sets the identifier and the checksum of the ICMPv6 header
+	msg := []byte{request, 0, 0, 0, 0, 0, 0, 1, 'n', 'e', 't', 's', 'c', 'a', 'n'}
+	if request == 8 {
+		sum := checksum(msg)
+		msg[2], msg[3] = byte(sum>>8), byte(sum)
+	}
+	_, zone, _ := strings.Cut(host, "%")
+
+	t := time.Now()
+	conn.SetDeadline(t.Add(timeout))
+	if _, err := conn.WriteTo(msg, &net.UDPAddr{IP: ip, Zone: zone}); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return 0, err
+		}
+		if addr, ok := from.(*net.UDPAddr); ok && addr.IP.Equal(ip) && n >= 8 && buf[0] == reply {
+			return time.Since(t), nil
+		}
+	}
+}
+
+// bindPing binds the ping socket fd to the interface and next source
+// address of src
+func bindPing(fd int, ip net.IP, src *sources) error {
+	if src.iface != "" {
+		if err := syscall.BindToDevice(fd, src.iface); err != nil {
+			return err
+		}
+	}
+	local := src.pick(ip)
+	if local == nil {
+		return nil
+	}
+	if ip4 := local.To4(); ip4 != nil {
+		sa := &syscall.SockaddrInet4{}
+		copy(sa.Addr[:], ip4)
+		return syscall.Bind(fd, sa)
+	}
+	sa := &syscall.SockaddrInet6{}
+	copy(sa.Addr[:], local)
+	return syscall.Bind(fd, sa)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package scan
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// ping is only implemented with the ping sockets of Linux
+func ping(ctx context.Context, host string, ip net.IP, src *sources, timeout time.Duration) (time.Duration, error) {
+	return 0, errPingUnsupported
+}
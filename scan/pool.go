@@ -0,0 +1,42 @@
+package scan
+
+import (
+	"context"
+	"sync"
+)
+
+// job is a probe of a port of a host, or of the host itself in discovery
+type job struct {
+	h    *Scanner
+	port int
+}
+
+// workers starts n goroutines calling work for every job until jobs is
+// closed. The returned WaitGroup is done when all workers have returned.
+func workers(n int, jobs <-chan job, work func(job)) *sync.WaitGroup {
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				work(j)
+			}
+		}()
+	}
+	return wg
+}
+
+// dispatch sends j to the next free worker once limiter allows, if not nil,
+// false if ctx is done before
+func dispatch(ctx context.Context, limiter *Limiter, jobs chan<- job, j job) bool {
+	if limiter != nil && limiter.Wait(ctx) != nil {
+		return false
+	}
+	select {
+	case jobs <- j:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,159 @@
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"netscan/services"
+)
+
+// MaxPort is the highest TCP and UDP port number
+const MaxPort = 65535
+
+// Description returns the name and description of the service registered
+// for port, or "" if unknown
+func Description(protocol string, port int) string {
+	if e, ok := services.Default().Lookup(protocol, port); ok {
+		return e.String()
+	}
+	return ""
+}
+
+// PortRange returns the ports from start to end
+func PortRange(start, end int) []int {
+	ports := make([]int, 0, end-start+1)
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// FormatPorts formats sorted ports as comma separated list of ports and
+// ranges, the inverse of ParsePorts
+func FormatPorts(ports []int) string {
+	var b strings.Builder
+	for i := 0; i < len(ports); i++ {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(ports[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(ports[j]))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// ParsePorts parses a comma separated list of ports, port ranges and service
+// names into a sorted list of ports. Items prefixed with "!" are excluded.
+//
+//	22,80,443,8000-8100      ports and ranges
+//	ssh,https,mysql          service names
+//	1-1024,!135-139          exclusions
+//
+// A list with exclusions only is applied to all ports.
+func ParsePorts(expr string) ([]int, error) {
+	var include, exclude []int
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		neg := strings.HasPrefix(item, "!")
+		ports, err := parsePortItem(strings.TrimPrefix(item, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if neg {
+			exclude = append(exclude, ports...)
+		} else {
+			include = append(include, ports...)
+		}
+	}
+	if include == nil && exclude == nil {
+		return nil, fmt.Errorf("no ports in %q", expr)
+	}
+	if include == nil {
+		include = PortRange(1, MaxPort)
+	}
+
+	excluded := make(map[int]bool, len(exclude))
+	for _, port := range exclude {
+		excluded[port] = true
+	}
+	sort.Ints(include)
+	ports := include[:0]
+	for i, port := range include {
+		if excluded[port] || i > 0 && port == include[i-1] {
+			continue
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("all ports in %q are excluded", expr)
+	}
+	return ports, nil
+}
+
+func parsePortItem(item string) ([]int, error) {
+	if item == "" {
+		return nil, fmt.Errorf("missing port after \"!\"")
+	}
+	if port, ok := portByName(item); ok {
+		return []int{port}, nil
+	}
+	start, end, found := strings.Cut(item, "-")
+	if !found {
+		// legacy start:end syntax
+		start, end, found = strings.Cut(item, ":")
+	}
+	if !found {
+		end = start
+	}
+	first, err := parsePort(start)
+	if err != nil {
+		return nil, err
+	}
+	last, err := parsePort(end)
+	if err != nil {
+		return nil, err
+	}
+	if last < first {
+		return nil, fmt.Errorf("invalid port range %q: end is before start", item)
+	}
+	return PortRange(first, last), nil
+}
+
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port or unknown service %q", s)
+	}
+	if port < 1 || port > MaxPort {
+		return 0, fmt.Errorf("port %d is not between 1 and %d", port, MaxPort)
+	}
+	return port, nil
+}
+
+// portByName looks up a service name of TCP or UDP
+func portByName(name string) (int, bool) {
+	db := services.Default()
+	if port, ok := db.Port("tcp", name); ok {
+		return port, true
+	}
+	return db.Port("udp", name)
+}
+
+// ServiceName returns the registered service name of port, or "" if unknown
+func ServiceName(protocol string, port int) string {
+	e, _ := services.Default().Lookup(protocol, port)
+	return e.Name
+}
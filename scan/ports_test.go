@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		expr  string
+		ports []int
+	}{
+		{"80", []int{80}},
+		{"10:12", []int{10, 11, 12}},
+		{"443,22,80,8000-8002", []int{22, 80, 443, 8000, 8001, 8002}},
+		{"ssh,HTTPS,mysql", []int{22, 443, 3306}},
+		{"130-140,!135-139", []int{130, 131, 132, 133, 134, 140}},
+		{"22,22,21-22", []int{21, 22}},
+	}
+	for _, test := range tests {
+		ports, err := ParsePorts(test.expr)
+		if err != nil {
+			t.Errorf("%q: %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(ports, test.ports) {
+			t.Errorf("%q: got %v, want %v", test.expr, ports, test.ports)
+		}
+	}
+
+	if ports, _ := ParsePorts("!1-1024"); len(ports) != MaxPort-1024 || ports[0] != 1025 {
+		t.Errorf("!1-1024: got %d ports starting at %d", len(ports), ports[0])
+	}
+
+	for _, expr := range []string{"", "0", "65536", "20-10", "nosuchservice", "!", "80,!80"} {
+		if _, err := ParsePorts(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+func TestTopPorts(t *testing.T) {
+	// the sizes nmap scans by default
+	for protocol, n := range map[string]int{"tcp": 1000, "udp": 100} {
+		seen := make(map[int]bool)
+		for _, port := range TopPorts(protocol, n) {
+			if seen[port] || port < 1 || port > MaxPort {
+				t.Errorf("bad top %s port %d", protocol, port)
+			}
+			seen[port] = true
+		}
+		if len(seen) != n {
+			t.Errorf("%d top %s ports", len(seen), protocol)
+		}
+	}
+	if ports := TopPorts("tcp", 3); !reflect.DeepEqual(ports, []int{80, 23, 443}) {
+		t.Errorf("got %v", ports)
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	for _, expr := range []string{"80", "1-1024,8080", "22,80,443,8000-8100", "1-65535"} {
+		ports, err := ParsePorts(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := FormatPorts(ports); s != expr {
+			t.Errorf("got %q, want %q", s, expr)
+		}
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	for port, name := range map[string]string{"1/tcp": "tcpmux", "22/tcp": "ssh", "512/tcp": "exec", "512/udp": "biff", "554/tcp": "rtsp", "4/tcp": ""} {
+		p, protocol, _ := strings.Cut(port, "/")
+		n, _ := strconv.Atoi(p)
+		if s := ServiceName(protocol, n); s != name {
+			t.Errorf("%s: got %q, want %q", port, s, name)
+		}
+	}
+}
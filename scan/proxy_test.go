@@ -0,0 +1,184 @@
+package scan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// socks5Server is a SOCKS5 proxy for IPv4 targets, requiring user and
+// password if user is set
+func socks5Server(t *testing.T, user, password string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				b := make([]byte, 3)
+				io.ReadFull(conn, b)
+				if user == "" {
+					conn.Write([]byte{5, 0})
+				} else {
+					conn.Write([]byte{5, 2})
+					field := func() string {
+						n := make([]byte, 1)
+						io.ReadFull(conn, n)
+						v := make([]byte, n[0])
+						io.ReadFull(conn, v)
+						return string(v)
+					}
+					conn.Read(make([]byte, 1)) // version
+					if field() != user || field() != password {
+						conn.Write([]byte{1, 1})
+						return
+					}
+					conn.Write([]byte{1, 0})
+				}
+				req := make([]byte, 4)
+				io.ReadFull(conn, req)
+				host := make([]byte, 4)
+				if req[3] == 3 {
+					n := make([]byte, 1)
+					io.ReadFull(conn, n)
+					host = make([]byte, n[0])
+				}
+				io.ReadFull(conn, host)
+				if req[3] == 1 {
+					host = []byte(net.IP(host).String())
+				} else if string(host) == "behind.proxy" {
+					// a name only the proxy knows
+					host = []byte("127.0.0.1")
+				}
+				port := make([]byte, 2)
+				io.ReadFull(conn, port)
+				addr := net.JoinHostPort(string(host), strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+				target, err := net.Dial("tcp", addr)
+				if err != nil {
+					reply := byte(1)
+					if errors.Is(err, syscall.ECONNREFUSED) {
+						reply = 5
+					}
+					conn.Write([]byte{5, reply, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer target.Close()
+				conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+				go io.Copy(target, conn)
+				io.Copy(conn, target)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// connectServer is an HTTP CONNECT proxy
+func connectServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer target.Close()
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	})}
+	go srv.Serve(l)
+	t.Cleanup(func() { srv.Close() })
+	return l.Addr().String()
+}
+
+func TestProxy(t *testing.T) {
+	_, open := listen(t, "127.0.0.1:0")
+	l, closed := listen(t, "127.0.0.1:0")
+	l.Close()
+
+	socks := socks5Server(t, "", "")
+	auth := socks5Server(t, "scan", "secret")
+	http := connectServer(t)
+	for _, expr := range []string{
+		"socks5://" + socks,
+		"socks5://scan:secret@" + auth,
+		"http://" + http,
+		"http://" + http + ",socks5://" + socks,
+	} {
+		chain, err := ParseProxies(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := DefaultOptions()
+		opts.Ports = []int{open, closed}
+		opts.ReportAll = true
+		opts.Proxies = chain
+
+		states := make(map[int]State)
+		for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+			states[r.Port] = r.State
+		}
+		if states[open] != Open || states[closed] != Closed {
+			t.Errorf("%s: got %v", expr, states)
+		}
+	}
+
+	// socks5h passes the name on
+	opts := DefaultOptions()
+	opts.Ports = []int{open}
+	opts.Proxies, _ = ParseProxies("socks5h://" + socks)
+	var found []Result
+	for r := range Scan(context.Background(), HostList{"behind.proxy"}, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || found[0].Host != "behind.proxy" || found[0].State != Open {
+		t.Errorf("socks5h: got %+v", found)
+	}
+
+	// a failed authentication tells nothing about the port
+	opts = DefaultOptions()
+	opts.Ports = []int{open}
+	opts.ReportAll = true
+	opts.Proxies, _ = ParseProxies("socks5://scan:wrong@" + auth)
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		if r.State != Error || r.Err == nil {
+			t.Errorf("wrong password: got %v", r.State)
+		}
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	chain, err := ParseProxies("socks5h://u:p@10.0.0.1:1080, http://proxy:3128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 2 || chain[0].String() != "socks5://10.0.0.1:1080" || chain[0].User != "u" || chain[0].Password != "p" || chain[1].Addr != "proxy:3128" || !chain[0].RemoteDNS || !chain[1].RemoteDNS {
+		t.Errorf("got %+v %+v", chain[0], chain[1])
+	}
+	if chain, _ := ParseProxies("socks5://10.0.0.1:1080"); chain[0].RemoteDNS {
+		t.Error("socks5 proxies do not resolve names")
+	}
+	for _, expr := range []string{"", "socks4://proxy:1080", "http://proxy", "proxy:1080"} {
+		if _, err := ParseProxies(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package scan
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket limiting the probes per second of all Scanners
+// of a scan.
+//
+// An adaptive Limiter changes its rate between a minimum and maximum: it is
+// increased while probes are answered and halved when the share of
+// unanswered probes grows, which is a sign of congestion or rate limiting by
+// the target.
+type Limiter struct {
+	mu       sync.Mutex
+	rate     float64
+	min, max float64
+	adaptive bool
+	// tokens may go negative, waiters then queue for future tokens
+	tokens float64
+	last   time.Time
+
+	// probes and drops of the current feedback window
+	probes, drops int
+	lastRatio     float64
+}
+
+// feedbackWindow is the number of probes between adaptive rate changes
+const feedbackWindow = 50
+
+// NewLimiter returns a Limiter for rate probes per second. 0 means no limit.
+func NewLimiter(rate float64) *Limiter {
+	return &Limiter{rate: rate, tokens: 1, last: time.Now()}
+}
+
+// NewAdaptiveLimiter returns a Limiter starting at rate which adapts between
+// min and max probes per second. A max of 0 means no upper bound. When rate
+// is 0 it starts at max, or at min if there is no upper bound.
+func NewAdaptiveLimiter(rate, min, max float64) *Limiter {
+	if max == 0 {
+		max = math.Inf(1)
+	}
+	if rate == 0 {
+		rate = max
+		if math.IsInf(max, 1) {
+			rate = min
+		}
+	}
+	l := NewLimiter(clamp(rate, min, max))
+	l.min, l.max, l.adaptive = min, max, true
+	return l
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
+
+// Rate returns the current probes per second
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait blocks until the next probe may be sent or ctx is done
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	if l.rate <= 0 || math.IsInf(l.rate, 1) {
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+	now := time.Now()
+	l.tokens = math.Min(1, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Feedback reports whether a probe went unanswered to an adaptive Limiter
+func (l *Limiter) Feedback(dropped bool) {
+	if !l.adaptive {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.probes++
+	if dropped {
+		l.drops++
+	}
+	if l.probes < feedbackWindow {
+		return
+	}
+	ratio := float64(l.drops) / float64(l.probes)
+	if ratio > l.lastRatio+0.1 {
+		l.rate = l.rate / 2
+	} else {
+		// additive increase of at least one probe per second
+		l.rate += math.Max(1, l.rate*0.05)
+	}
+	l.rate = clamp(l.rate, l.min, l.max)
+	l.probes, l.drops, l.lastRatio = 0, 0, ratio
+}
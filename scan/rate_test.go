@@ -0,0 +1,73 @@
+package scan
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(200)
+	ctx := context.Background()
+	start := time.Now()
+	for i := 0; i < 41; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first token is available at once
+	if d := time.Since(start); d < 190*time.Millisecond || d > 400*time.Millisecond {
+		t.Errorf("41 probes at 200/s took %v", d)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := NewLimiter(0.1).Wait(ctx); err == nil {
+		t.Error("expected canceled wait")
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := NewAdaptiveLimiter(0, 10, 100)
+	if l.Rate() != 100 {
+		t.Fatalf("start rate %v", l.Rate())
+	}
+	feed := func(dropped bool) {
+		for i := 0; i < feedbackWindow; i++ {
+			l.Feedback(dropped)
+		}
+	}
+	feed(true)
+	if l.Rate() != 50 {
+		t.Errorf("rate after drops %v", l.Rate())
+	}
+	// steady loss is not congestion
+	feed(true)
+	if l.Rate() <= 50 {
+		t.Errorf("rate after steady drops %v", l.Rate())
+	}
+	for i := 0; i < 100; i++ {
+		feed(false)
+	}
+	if l.Rate() != 100 {
+		t.Errorf("rate is not capped: %v", l.Rate())
+	}
+	for i := 0; i < 10; i++ {
+		feed(false)
+		feed(true)
+	}
+	if l.Rate() < 10 {
+		t.Errorf("rate below minimum: %v", l.Rate())
+	}
+
+	if l := NewAdaptiveLimiter(0, 10, 0); l.Rate() != 10 {
+		t.Errorf("start rate without maximum %v", l.Rate())
+	}
+	fixed := NewLimiter(5)
+	for i := 0; i < feedbackWindow; i++ {
+		fixed.Feedback(true)
+	}
+	if fixed.Rate() != 5 {
+		t.Errorf("fixed rate changed %v", fixed.Rate())
+	}
+}
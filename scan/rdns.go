@@ -0,0 +1,83 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reverseDNS looks up the PTR names of each address once per scan
+type reverseDNS struct {
+	resolver *net.Resolver
+	timeout  time.Duration
+
+	mu      sync.Mutex
+	lookups map[string]*ptrLookup
+}
+
+type ptrLookup struct {
+	once  sync.Once
+	names []string
+}
+
+func newReverseDNS(opts *Options) *reverseDNS {
+	r := &reverseDNS{
+		resolver: opts.Resolver,
+		timeout:  opts.ReverseDNSTimeout,
+		lookups:  make(map[string]*ptrLookup),
+	}
+	if r.resolver == nil {
+		r.resolver = net.DefaultResolver
+	}
+	return r
+}
+
+// names returns the PTR names of ip without the trailing dot. Concurrent
+// calls for the same address wait for a single lookup.
+func (r *reverseDNS) names(ip net.IP) []string {
+	addr := ip.String()
+	r.mu.Lock()
+	l := r.lookups[addr]
+	if l == nil {
+		l = &ptrLookup{}
+		r.lookups[addr] = l
+	}
+	r.mu.Unlock()
+
+	l.once.Do(func() {
+		ctx := context.Background()
+		if r.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+		}
+		names, _ := r.resolver.LookupAddr(ctx, addr)
+		for _, name := range names {
+			l.names = append(l.names, strings.TrimSuffix(name, "."))
+		}
+	})
+	return l.names
+}
+
+// addNames passes the results of probed on to results, adding the PTR names
+// of open ports looked up in goroutines of their own. results is closed
+// when probed is and all lookups are done.
+func (r *reverseDNS) addNames(probed <-chan Result, results chan<- Result) {
+	var wg sync.WaitGroup
+	for res := range probed {
+		if res.State != Open || res.IP == nil {
+			results <- res
+			continue
+		}
+		wg.Add(1)
+		go func(res Result) {
+			defer wg.Done()
+			res.Names = r.names(res.IP)
+			results <- res
+		}(res)
+	}
+	wg.Wait()
+	close(results)
+}
@@ -0,0 +1,54 @@
+package scan
+
+import (
+	"sync"
+	"time"
+)
+
+// rtt estimates the round trip time of a host from the connect times of
+// answered probes, like TCP does for retransmissions (RFC 6298)
+type rtt struct {
+	mu      sync.Mutex
+	srtt    time.Duration
+	rttvar  time.Duration
+	samples int
+}
+
+// update the estimate with a measured round trip time
+func (r *rtt) update(sample time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.samples == 0 {
+		r.srtt = sample
+		r.rttvar = sample / 2
+	} else {
+		// rttvar = 3/4 rttvar + 1/4 |srtt - sample|, srtt = 7/8 srtt + 1/8 sample
+		delta := r.srtt - sample
+		if delta < 0 {
+			delta = -delta
+		}
+		r.rttvar = (3*r.rttvar + delta) / 4
+		r.srtt = (7*r.srtt + sample) / 8
+	}
+	r.samples++
+}
+
+// timeout returns srtt + 4 * rttvar within min and max, or initial as long
+// as there are no samples
+func (r *rtt) timeout(initial, min, max time.Duration) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	to := initial
+	if r.samples > 0 {
+		to = r.srtt + 4*r.rttvar
+	}
+	if to < min {
+		to = min
+	}
+	if max > 0 && to > max {
+		to = max
+	}
+	return to
+}
@@ -0,0 +1,51 @@
+package scan
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRTT(t *testing.T) {
+	r := &rtt{}
+	if to := r.timeout(3*time.Second, 100*time.Millisecond, 10*time.Second); to != 3*time.Second {
+		t.Errorf("initial timeout %v", to)
+	}
+
+	r.update(200 * time.Millisecond)
+	if r.srtt != 200*time.Millisecond || r.rttvar != 100*time.Millisecond {
+		t.Errorf("first sample: srtt %v rttvar %v", r.srtt, r.rttvar)
+	}
+	for i := 0; i < 50; i++ {
+		r.update(200 * time.Millisecond)
+	}
+	// the variance decays with stable samples
+	if to := r.timeout(time.Second, 0, 0); to < 200*time.Millisecond || to > 210*time.Millisecond {
+		t.Errorf("stable timeout %v", to)
+	}
+	if to := r.timeout(time.Second, 300*time.Millisecond, 0); to != 300*time.Millisecond {
+		t.Errorf("timeout below minimum %v", to)
+	}
+
+	r.update(2 * time.Second)
+	if to := r.timeout(time.Second, 0, time.Second); to != time.Second {
+		t.Errorf("timeout above maximum %v", to)
+	}
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.AdaptiveTimeout = true
+	h := New("127.0.0.1", opts)
+	if h.timeout() != opts.Timeout {
+		t.Errorf("initial timeout %v", h.timeout())
+	}
+	if r := h.connect(context.Background(), port); r.State != Open {
+		t.Fatalf("got %+v", r)
+	}
+	if h.timeout() != opts.MinTimeout {
+		t.Errorf("timeout after loopback connect %v", h.timeout())
+	}
+}
@@ -0,0 +1,449 @@
+// Package scan implements a concurrent TCP and UDP port scanner.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"netscan/detect"
+	"netscan/target"
+)
+
+// State of a scanned port
+type State int
+
+// Port states
+const (
+	// Open ports accepted the connection
+	Open State = iota
+	// Closed ports refused the connection
+	Closed
+	// Filtered ports did not answer before the timeout
+	Filtered
+	// Unreachable ports are behind a host or network unreachable error
+	Unreachable
+	// OpenFiltered UDP ports did not answer, they may be open or filtered
+	OpenFiltered
+	// Error ports could not be probed because of a local error, see
+	// Result.Err
+	Error
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case Closed:
+		return "closed"
+	case Filtered:
+		return "filtered"
+	case Unreachable:
+		return "unreachable"
+	case OpenFiltered:
+		return "open|filtered"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *State) UnmarshalText(text []byte) error {
+	for state := Open; state <= Error; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown port state %q", text)
+}
+
+// classify the state of a port by the error of the connection attempt
+func classify(err error) State {
+	switch {
+	case err == nil:
+		return Open
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return Closed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTDOWN):
+		return Unreachable
+	case timeout(err):
+		return Filtered
+	}
+	return Error
+}
+
+// timeout reports whether err is a timeout rather than a local failure
+func timeout(err error) bool {
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout() || errors.Is(err, syscall.ETIMEDOUT)
+}
+
+// Result of a single port probe
+type Result struct {
+	Host        string
+	IP          net.IP
+	Port        int
+	Protocol    string
+	State       State
+	Latency     time.Duration
+	Description string
+	// Banner is the sanitized greeting of an open port, see Options.Banner
+	Banner string
+	// Service detected on an open port, see Options.Detect
+	Service *detect.Service
+	// Names of IP found by reverse DNS, see Options.ReverseDNS
+	Names []string
+	// Err is the local error of ports in the Error state
+	Err error
+}
+
+// Options ...
+type Options struct {
+	Ports []int
+	// Threads is the number of workers probing concurrently
+	Threads int
+	// Timeout of a probe, the initial timeout with AdaptiveTimeout
+	Timeout time.Duration
+	// AdaptiveTimeout derives the timeout of each host from its measured
+	// round trip times, within MinTimeout and MaxTimeout. In random order
+	// only the most recently probed hosts are remembered.
+	AdaptiveTimeout        bool
+	MinTimeout, MaxTimeout time.Duration
+	// ReportAll sends results in every state, not only open ports
+	ReportAll bool
+	// UDP scans UDP instead of TCP ports
+	UDP bool
+	// Randomize probes the host and port pairs in a pseudo random order
+	// derived from Seed, which spreads the load over all hosts
+	Randomize bool
+	Seed      int64
+	// Shard of Shards scans only its part of the probes, the Shards
+	// processes with Shard 1 to Shards and the same Seed scan every probe
+	// exactly once. Discover shards the hosts.
+	Shard, Shards int
+	// Resume skips the probes before this position of Stats.Position of an
+	// interrupted scan with the same targets and options
+	Resume uint64
+	// Stats are updated while scanning if not nil
+	Stats *Stats
+
+	// Rate limits the probes per second of the whole scan, 0 is unlimited
+	Rate float64
+	// MinRate and MaxRate make the rate adaptive within these bounds,
+	// starting at Rate if set. A MaxRate of 0 means no upper bound.
+	MinRate, MaxRate float64
+
+	// Banner reads the first bytes an open port sends
+	Banner bool
+	// BannerTimeout limits the time to wait for a banner
+	BannerTimeout time.Duration
+	// BannerSize is the maximum number of bytes read
+	BannerSize int
+	// BannerProbe is sent when the service stays silent, if not empty
+	BannerProbe []byte
+
+	// Detect identifies the service of open ports with the signatures of
+	// the database if not nil. Responses are read for BannerTimeout.
+	Detect *detect.DB
+
+	// ReverseDNS looks up the PTR names of hosts with open ports with
+	// Resolver, nil is the system resolver, for at most ReverseDNSTimeout
+	ReverseDNS        bool
+	ReverseDNSTimeout time.Duration
+	Resolver          *net.Resolver
+
+	// Proxies is a chain of proxies TCP probes connect through, see
+	// ParseProxies. Errors replied by the last proxy decide the state of
+	// the port.
+	Proxies []*Proxy
+
+	// SourceIPs are the local addresses probes are sent from, in turn for
+	// each address family. Interface binds the probes to a network
+	// interface, on Linux with SO_BINDTODEVICE and elsewhere by sending
+	// from its addresses.
+	SourceIPs []net.IP
+	Interface string
+
+	// DiscoveryPorts are connected to by Discover, a host answering on any
+	// of them is up
+	DiscoveryPorts []int
+}
+
+// DefaultOptions returns the options used by the netscan command
+func DefaultOptions() Options {
+	return Options{
+		Ports:             PortRange(1, MaxPort),
+		Threads:           100,
+		Timeout:           3 * time.Second,
+		MinTimeout:        100 * time.Millisecond,
+		MaxTimeout:        10 * time.Second,
+		BannerTimeout:     2 * time.Second,
+		BannerSize:        512,
+		ReverseDNSTimeout: 2 * time.Second,
+		// web, ssh and smb answer on most servers and workstations
+		DiscoveryPorts: []int{80, 443, 22, 445},
+	}
+}
+
+// Scanner ...
+type Scanner struct {
+	host string
+	ip   net.IP
+	// addr is dialed, the address of resolved hosts
+	addr string
+	// port is the only port probed on hosts of [address]:port targets
+	port    int
+	opts    *Options
+	limiter *Limiter
+	rtt     *rtt
+	src     *sources
+	// pending probes of the host, plus one while more are dispatched
+	pending atomic.Int64
+}
+
+// New Scanner
+func New(host string, opts Options) *Scanner {
+	return newScanner(target.Host{Name: host}, &opts, newSources(&opts))
+}
+
+// newScanner of a host of Targets, sharing opts and the source addresses src
+// with the other hosts
+func newScanner(host target.Host, opts *Options, src *sources) *Scanner {
+	h := &Scanner{
+		host: host.Name,
+		addr: host.Name,
+		port: host.Port,
+		opts: opts,
+		rtt:  &rtt{},
+		src:  src,
+	}
+	if host.Addr.IsValid() {
+		h.ip = net.IP(host.Addr.AsSlice())
+		h.addr = host.Addr.String()
+	} else {
+		// strip the zone of IPv6 link-local addresses
+		ip, _, _ := strings.Cut(host.Name, "%")
+		h.ip = net.ParseIP(ip)
+	}
+	return h
+}
+
+// newLimiter returns the Limiter for the rate options, nil if unlimited
+func (opts *Options) newLimiter() *Limiter {
+	if opts.MinRate > 0 || opts.MaxRate > 0 {
+		return NewAdaptiveLimiter(opts.Rate, opts.MinRate, opts.MaxRate)
+	}
+	if opts.Rate > 0 {
+		return NewLimiter(opts.Rate)
+	}
+	return nil
+}
+
+// Targets is an indexed list of hosts, implemented by target.List which
+// generates hosts on demand
+type Targets interface {
+	Len() uint64
+	Host(i uint64) target.Host
+}
+
+// HostList is a Targets of host names and addresses
+type HostList []string
+
+// Len returns the number of hosts
+func (l HostList) Len() uint64 {
+	return uint64(len(l))
+}
+
+// Host returns host i
+func (l HostList) Host(i uint64) target.Host {
+	return target.Host{Name: l[i]}
+}
+
+// Scan all hosts with opts and stream open ports, or all ports with
+// opts.ReportAll, over the returned channel. Probes are run by opts.Threads
+// workers. When ctx is canceled no more probes are started, the probes in
+// flight finish and send their results. The channel is closed when all
+// probes are done and must be read until then.
+func Scan(ctx context.Context, hosts Targets, opts Options) <-chan Result {
+	if opts.Threads < 1 {
+		opts.Threads = 1
+	}
+	results := make(chan Result)
+	jobs := make(chan job)
+	probed := results
+	if opts.ReverseDNS {
+		// names are looked up apart from the workers, which keep probing
+		probed = make(chan Result)
+		go newReverseDNS(&opts).addNames(probed, results)
+	}
+
+	wg := workers(opts.Threads, jobs, func(j job) {
+		// started probes are not canceled with ctx
+		r := j.h.connect(context.Background(), j.port)
+		if j.h.limiter != nil {
+			j.h.limiter.Feedback(r.State == Filtered || r.State == OpenFiltered)
+		}
+		if r.State == Open {
+			opts.Stats.found()
+		}
+		if !opts.Randomize {
+			j.h.release(opts.Stats)
+		}
+		if r.State == Open || r.State == Error || opts.ReportAll {
+			probed <- r
+		}
+	})
+	// probe i is port i % len(ports) of host i / len(ports)
+	nports := uint64(len(opts.Ports))
+	seq := opts.newSequence(hosts.Len() * nports)
+	var done uint64
+	if !opts.Randomize && nports > 0 {
+		done = seq.Pos() / nports
+	}
+	opts.Stats.start(seq.Len(), hosts.Len(), done, seq.Pos())
+
+	go func() {
+		wg.Wait()
+		// random order does not tell when a host is done
+		if opts.Randomize && ctx.Err() == nil && opts.Stats != nil {
+			opts.Stats.HostsDone.Store(hosts.Len())
+		}
+		close(probed)
+	}()
+
+	go func() {
+		defer close(jobs)
+		// one limiter and turn of source addresses for all hosts
+		limiter := opts.newLimiter()
+		src := newSources(&opts)
+
+		// in order the Scanner of the current host is enough, random order
+		// keeps the Scanners of recent hosts for their round trip times
+		var scan *Scanner
+		var current uint64
+		scanners := newScannerCache(maxScanners)
+		for {
+			i, ok := seq.Next()
+			if !ok {
+				if scan != nil && !opts.Randomize {
+					scan.release(opts.Stats)
+				}
+				return
+			}
+			if host := i / nports; scan == nil || host != current {
+				if scan != nil && !opts.Randomize {
+					// in order all probes of the host are dispatched
+					scan.release(opts.Stats)
+				}
+				scan, current = scanners.get(host), host
+				if scan == nil {
+					scan = newScanner(hosts.Host(host), &opts, src)
+					scan.limiter = limiter
+					scan.pending.Add(1)
+					if opts.Randomize {
+						scanners.add(host, scan)
+					}
+				}
+			}
+			port := opts.Ports[i%nports]
+			if scan.port != 0 {
+				// the first probe of the host goes to its own port
+				if i%nports != 0 {
+					continue
+				}
+				port = scan.port
+			}
+			scan.pending.Add(1)
+			if !dispatch(ctx, limiter, jobs, job{h: scan, port: port}) {
+				return
+			}
+			opts.Stats.sent(seq.Pos())
+		}
+	}()
+
+	return results
+}
+
+// timeout returns the probe timeout of the host
+func (h *Scanner) timeout() time.Duration {
+	if !h.opts.AdaptiveTimeout {
+		return h.opts.Timeout
+	}
+	return h.rtt.timeout(h.opts.Timeout, h.opts.MinTimeout, h.opts.MaxTimeout)
+}
+
+// dial connects to addr within the probe timeout from the next source
+// address, through Options.Proxies for TCP
+func (h *Scanner) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network == "tcp" && len(h.opts.Proxies) > 0 {
+		host, _, _ := net.SplitHostPort(h.opts.Proxies[0].Addr)
+		d := h.src.dialer(network, net.ParseIP(host))
+		d.Timeout = h.timeout()
+		return dialProxies(ctx, d, h.opts.Proxies, addr, h.timeout())
+	}
+	d := h.src.dialer(network, h.ip)
+	d.Timeout = h.timeout()
+	return d.DialContext(ctx, network, addr)
+}
+
+// Probe a single port of the host, without the rate limits of the Options
+func (h *Scanner) Probe(ctx context.Context, port int) Result {
+	return h.connect(ctx, port)
+}
+
+// connect ...
+func (h *Scanner) connect(ctx context.Context, port int) Result {
+	if h.opts.UDP {
+		return h.probeUDP(ctx, port)
+	}
+	r := Result{
+		Host:        h.host,
+		IP:          h.ip,
+		Port:        port,
+		Protocol:    "tcp",
+		Description: Description("tcp", port),
+	}
+	addr := net.JoinHostPort(h.addr, strconv.Itoa(port))
+	t := time.Now()
+	conn, err := h.dial(ctx, "tcp", addr)
+	r.State = classify(err)
+	if r.State == Error {
+		r.Err = err
+	}
+	if r.State == Open || r.State == Closed {
+		r.Latency = time.Since(t)
+		h.rtt.update(r.Latency)
+	}
+	if err != nil {
+		return r
+	}
+	defer conn.Close()
+	if !h.opts.Banner && h.opts.Detect == nil {
+		return r
+	}
+
+	greeting, banner := grabBanner(conn, h.opts)
+	if h.opts.Banner {
+		r.Banner = Sanitize(banner)
+	}
+	if h.opts.Detect != nil {
+		dial := func(ctx context.Context) (net.Conn, error) {
+			return h.dial(ctx, "tcp", addr)
+		}
+		r.Service = h.opts.Detect.Detect(ctx, port, greeting, dial, h.opts.BannerTimeout)
+	}
+	return r
+}
@@ -0,0 +1,252 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"netscan/target"
+)
+
+func listen(t *testing.T, addr string) (net.Listener, int) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return l, l.Addr().(*net.TCPAddr).Port
+}
+
+func TestScan(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.Ports = PortRange(port-2, port+2)
+	opts.Timeout = time.Second
+
+	var found []Result
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || found[0].Port != port || found[0].State != Open {
+		t.Fatalf("expected open port %d, got %+v", port, found)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	l, port := listen(t, "127.0.0.1:0")
+
+	h := New("127.0.0.1", DefaultOptions())
+	if r := h.Probe(context.Background(), port); r.State != Open || r.Port != port || r.Host != "127.0.0.1" {
+		t.Errorf("got %+v", r)
+	}
+	l.Close()
+	if r := h.Probe(context.Background(), port); r.State != Closed {
+		t.Errorf("closed port: got %+v", r)
+	}
+}
+
+func TestScanHostPort(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.Ports = []int{port + 1, port + 2}
+	opts.ReportAll = true
+	hosts := target.Hosts{{Name: "127.0.0.1", Port: port}, {Name: "127.0.0.2"}}
+	probed := make(map[string][]int)
+	for r := range Scan(context.Background(), hosts, opts) {
+		probed[r.Host] = append(probed[r.Host], r.Port)
+	}
+	if len(probed["127.0.0.1"]) != 1 || probed["127.0.0.1"][0] != port || len(probed["127.0.0.2"]) != 2 {
+		t.Errorf("got %v", probed)
+	}
+}
+
+func TestScanIP6(t *testing.T) {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("no IPv6 loopback")
+	}
+	l.Close()
+	_, port := listen(t, "[::1]:0")
+
+	opts := DefaultOptions()
+	opts.Ports = []int{port}
+
+	var found []Result
+	for r := range Scan(context.Background(), HostList{"::1"}, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || !found[0].IP.Equal(net.IPv6loopback) {
+		t.Fatalf("expected open port %d on ::1, got %+v", port, found)
+	}
+}
+
+func TestScanReportAll(t *testing.T) {
+	l, port := listen(t, "127.0.0.1:0")
+	l.Close()
+
+	opts := DefaultOptions()
+	opts.Ports = []int{port}
+	opts.ReportAll = true
+
+	var found []Result
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || found[0].State != Closed {
+		t.Fatalf("expected closed port %d, got %+v", port, found)
+	}
+}
+
+func TestScanResolved(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.Ports = []int{port}
+	hosts := target.Hosts{{Name: "localhost", Addr: netip.MustParseAddr("127.0.0.1")}}
+
+	var found []Result
+	for r := range Scan(context.Background(), hosts, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || found[0].Host != "localhost" || found[0].IP.String() != "127.0.0.1" {
+		t.Fatalf("expected open port of localhost at 127.0.0.1, got %+v", found)
+	}
+}
+
+func TestScanComplete(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Ports = PortRange(1, 2000)
+	opts.ReportAll = true
+	opts.Threads = 50
+
+	seen := make(map[int]bool)
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		seen[r.Port] = true
+	}
+	if len(seen) != 2000 {
+		t.Errorf("expected 2000 results, got %d", len(seen))
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := DefaultOptions()
+	opts.ReportAll = true
+
+	n := 0
+	for range Scan(ctx, HostList{"127.0.0.1"}, opts) {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	if n >= MaxPort {
+		t.Errorf("scan not canceled")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		state State
+	}{
+		{nil, Open},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, Closed},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, Unreachable},
+		{&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, Filtered},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ETIMEDOUT)}, Filtered},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("socket", syscall.EMFILE)}, Error},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("bind", syscall.EADDRNOTAVAIL)}, Error},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EPERM)}, Error},
+	}
+	for _, test := range tests {
+		if state := classify(test.err); state != test.state {
+			t.Errorf("%v: got %v, want %v", test.err, state, test.state)
+		}
+	}
+}
+
+func TestReverseDNS(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	opts := DefaultOptions()
+	opts.Ports = []int{port}
+	opts.ReverseDNS = true
+	// localhost comes from /etc/hosts, without a DNS server
+	opts.Resolver, _ = target.NewResolver("127.0.0.1:1")
+
+	var found []Result
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		found = append(found, r)
+	}
+	if len(found) != 1 || len(found[0].Names) == 0 || found[0].Names[0] != "localhost" {
+		t.Errorf("expected name localhost, got %+v", found)
+	}
+}
+
+func TestReverseDNSSlow(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.3:0")
+	if err != nil {
+		t.Skip("no 127.0.0.3:", err)
+	}
+	defer l.Close()
+	closed, port := listen(t, "127.0.0.3:0")
+	closed.Close()
+
+	opts := DefaultOptions()
+	opts.Ports = []int{l.Addr().(*net.TCPAddr).Port, port}
+	opts.Threads = 1
+	opts.ReportAll = true
+	opts.ReverseDNS = true
+	opts.ReverseDNSTimeout = 1500 * time.Millisecond
+	// a DNS server which never answers
+	opts.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	// the lookup of the open port does not hold up the worker
+	start := time.Now()
+	results := Scan(context.Background(), HostList{"127.0.0.3"}, opts)
+	if r := <-results; r.State != Closed || time.Since(start) > time.Second {
+		t.Errorf("got %+v after %v", r, time.Since(start))
+	}
+	for range results {
+	}
+}
+
+func TestScanStats(t *testing.T) {
+	_, port := listen(t, "127.0.0.1:0")
+
+	for _, randomize := range []bool{false, true} {
+		opts := DefaultOptions()
+		opts.Ports = []int{port, port + 1}
+		opts.Randomize = randomize
+		opts.Stats = &Stats{}
+		for range Scan(context.Background(), HostList{"127.0.0.1", "127.0.0.1", "127.0.0.1"}, opts) {
+		}
+		s := opts.Stats
+		if s.Total.Load() != 6 || s.Position.Load() != 6 || s.Sent.Load() != 6 || s.Found.Load() != 3 || s.Hosts.Load() != 3 || s.HostsDone.Load() != 3 {
+			t.Errorf("randomize %v: got total %d, position %d, sent %d, found %d, hosts %d/%d", randomize,
+				s.Total.Load(), s.Position.Load(), s.Sent.Load(), s.Found.Load(), s.HostsDone.Load(), s.Hosts.Load())
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package scan
+
+import (
+	"net"
+	"sync/atomic"
+	"syscall"
+)
+
+// sources hands out the source addresses of the probes in turn, for each
+// address family
+type sources struct {
+	v4, v6       []net.IP
+	next4, next6 atomic.Uint64
+	// control binds sockets to iface
+	iface   string
+	control func(network, address string, c syscall.RawConn) error
+}
+
+// newSources returns the source addresses of opts.SourceIPs. Where sockets
+// can not be bound to an interface the addresses of Options.Interface are
+// used instead.
+func newSources(opts *Options) *sources {
+	s := &sources{}
+	ips := opts.SourceIPs
+	if opts.Interface != "" {
+		if canBindToDevice {
+			s.iface, s.control = opts.Interface, bindToDevice(opts.Interface)
+		} else if len(ips) == 0 {
+			ips = interfaceIPs(opts.Interface)
+		}
+	}
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			s.v4 = append(s.v4, ip)
+		} else {
+			s.v6 = append(s.v6, ip)
+		}
+	}
+	return s
+}
+
+// pick returns the next source address of the family of ip, nil to leave
+// the choice to the system. IPv4 is preferred for host names.
+func (s *sources) pick(ip net.IP) net.IP {
+	ips, next := s.v4, &s.next4
+	if ip != nil && ip.To4() == nil || ip == nil && len(s.v4) == 0 {
+		ips, next = s.v6, &s.next6
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	return ips[(next.Add(1)-1)%uint64(len(ips))]
+}
+
+// dialer returns a dialer from the next source address for ip
+func (s *sources) dialer(network string, ip net.IP) *net.Dialer {
+	d := &net.Dialer{Control: s.control}
+	if src := s.pick(ip); src != nil {
+		if network == "udp" {
+			d.LocalAddr = &net.UDPAddr{IP: src}
+		} else {
+			d.LocalAddr = &net.TCPAddr{IP: src}
+		}
+	}
+	return d
+}
+
+// interfaceIPs returns the unicast addresses of the interface name
+func interfaceIPs(name string) []net.IP {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLinkLocalUnicast() {
+			ips = append(ips, n.IP)
+		}
+	}
+	return ips
+}
@@ -0,0 +1,46 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestSourceIPs(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	from := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			from <- conn.RemoteAddr().(*net.TCPAddr).IP.String()
+			conn.Close()
+		}
+	}()
+
+	opts := DefaultOptions()
+	opts.Ports = []int{l.Addr().(*net.TCPAddr).Port}
+	opts.SourceIPs = []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3"), net.ParseIP("::1")}
+	seen := make(map[string]int)
+	for range Scan(context.Background(), HostList{"127.0.0.1", "127.0.0.1", "127.0.0.1", "127.0.0.1"}, opts) {
+		seen[<-from]++
+	}
+	if seen["127.0.0.2"] != 2 || seen["127.0.0.3"] != 2 {
+		t.Errorf("got %v", seen)
+	}
+
+	src := newSources(&opts)
+	var got []string
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "2001:db8::1", "10.0.0.3"} {
+		got = append(got, src.pick(net.ParseIP(ip)).String())
+	}
+	if got[0] != "127.0.0.2" || got[1] != "127.0.0.3" || got[2] != "::1" || got[3] != "127.0.0.2" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,60 @@
+package scan
+
+import "sync/atomic"
+
+// Stats of a running scan or discovery, safe for concurrent use. Scan and
+// Discover reset them when they start.
+type Stats struct {
+	// Position after the last probe started, to resume from, of Total
+	// positions of the probe order
+	Position atomic.Uint64
+	Total    atomic.Uint64
+	// Sent probes, or hosts probed by discovery
+	Sent atomic.Uint64
+	// Found open ports, or hosts up
+	Found atomic.Uint64
+	// HostsDone of Hosts have all probes finished. In random order they are
+	// only counted when the scan is done.
+	Hosts     atomic.Uint64
+	HostsDone atomic.Uint64
+}
+
+// start resets the stats at position pos, where done hosts were done
+// before
+func (s *Stats) start(total, hosts, done, pos uint64) {
+	if s != nil {
+		s.Position.Store(pos)
+		s.Total.Store(total)
+		s.Sent.Store(0)
+		s.Found.Store(0)
+		s.Hosts.Store(hosts)
+		s.HostsDone.Store(done)
+	}
+}
+
+func (s *Stats) sent(pos uint64) {
+	if s != nil {
+		s.Position.Store(pos)
+		s.Sent.Add(1)
+	}
+}
+
+func (s *Stats) found() {
+	if s != nil {
+		s.Found.Add(1)
+	}
+}
+
+func (s *Stats) hostDone() {
+	if s != nil {
+		s.HostsDone.Add(1)
+	}
+}
+
+// release a probe, or the dispatch, of the host and count the host when
+// it was the last
+func (h *Scanner) release(s *Stats) {
+	if h.pending.Add(-1) == 0 {
+		s.hostDone()
+	}
+}
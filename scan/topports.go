@@ -0,0 +1,145 @@
+package scan
+
+import "netscan/services"
+
+// TopPorts returns the n ports of protocol most frequently found open, most
+// frequent first. The frequencies of the services database are used when it
+// has them, as with nmap-services.
+func TopPorts(protocol string, n int) []int {
+	if ports := services.Default().Top(protocol, n); ports != nil {
+		return ports
+	}
+	top := topPorts
+	if protocol == "udp" {
+		top = topUDPPorts
+	}
+	if n > len(top) {
+		n = len(top)
+	}
+	ports := make([]int, n)
+	copy(ports, top[:n])
+	return ports
+}
+
+// topUDPPorts is ranked like topPorts
+var topUDPPorts = []int{
+	631, 161, 137, 123, 138, 1434, 445, 135, 67, 53,
+	139, 500, 68, 520, 1900, 4500, 514, 49152, 162, 69,
+	// the rest of the nmap top 100 UDP ports, in port order
+	7, 9, 17, 19, 49, 80, 88, 111, 120, 136,
+	158, 177, 427, 443, 497, 515, 518, 593, 623, 626,
+	996, 997, 998, 999, 1022, 1023, 1025, 1026, 1027, 1028,
+	1029, 1030, 1433, 1645, 1646, 1701, 1718, 1719, 1812, 1813,
+	2000, 2048, 2049, 2222, 2223, 3283, 3456, 3703, 4444, 5000,
+	5060, 5353, 5632, 9200, 10000, 17185, 20031, 30718, 31337, 32768,
+	32769, 32771, 32815, 33281, 49153, 49154, 49156, 49181, 49182, 49185,
+	49186, 49188, 49190, 49191, 49192, 49193, 49194, 49200, 49201, 65024,
+}
+
+// topPorts is ranked by how often the port is found open on internet
+// facing hosts. After the ranked ports follow the others nmap scans by
+// default, so the common sizes of --top-ports are covered.
+var topPorts = []int{
+	80, 23, 443, 21, 22, 25, 3389, 110, 445, 139,
+	143, 53, 135, 3306, 8080, 1723, 111, 995, 993, 5900,
+	1025, 587, 8888, 199, 1720, 465, 548, 113, 81, 6001,
+	10000, 514, 5060, 179, 1026, 2000, 8443, 8000, 32768, 554,
+	26, 1433, 49152, 2001, 515, 8008, 49154, 1027, 5666, 646,
+	5000, 5631, 631, 49153, 8081, 2049, 88, 79, 5800, 106,
+	2121, 1110, 49155, 6000, 513, 990, 5357, 427, 49156, 543,
+	544, 5101, 144, 7, 389, 8009, 3128, 444, 9999, 5009,
+	7070, 5190, 3000, 5432, 1900, 3986, 13, 1029, 9, 5051,
+	6646, 49157, 1028, 873, 1755, 2717, 4899, 9100, 119, 37,
+	1000, 3001, 5001, 82, 10010, 1030, 9090, 2107, 1024, 2103,
+	6004, 1801, 5050, 19, 8031, 1041, 255, 1049, 1048, 2967,
+	1053, 3703, 1056, 1065, 1064, 1054, 17, 808, 3689, 1031,
+	1044, 1071, 5901, 100, 9102, 8010, 2869, 1039, 5120, 4001,
+	9000, 2105, 636, 1038, 2601, 1, 7000, 1066, 1069, 625,
+	311, 280, 254, 4000, 1993, 1761, 5003, 2002, 2005, 1998,
+	1032, 1050, 6112, 3690, 1521, 2161, 6002, 1080, 2401, 4045,
+	902, 7937, 787, 1058, 2383, 32771, 1033, 1040, 1059, 50000,
+	5555, 10001, 1494, 593, 2301, 3, 1100, 3268, 7938, 1234,
+	1022, 1074, 8002, 1036, 1035, 9001, 1037, 464, 497, 1935,
+	6666, 6543, 24, 1352, 3269, 1111, 407, 500, 20, 2006,
+	3260, 15000, 1218, 1034, 4444, 264, 2004, 33, 1042, 42510,
+	999, 3052, 1023, 1068, 222, 7100, 888, 563, 1717, 2008,
+	992, 32770, 7001, 2007, 8082, 5550, 2009, 5801, 1043, 512,
+	2701, 7019, 50001, 1700, 4662, 2065, 2010, 42, 9535, 2602,
+	3333, 161, 5100, 5002, 2604, 4002, 6059, 1047, 8192, 8193,
+	2702, 6789, 9595, 1051, 9594, 9593, 16993, 16992, 5226, 5225,
+	32769, 3283, 1052, 8194, 1055, 1062, 9415, 8701, 8652, 8651,
+	8089, 65389, 65000, 64680, 64623, 55600, 55555, 52869, 35500, 33354,
+	23502, 20828, 1311, 1060, 4443, 730, 731, 709, 1067, 13782,
+	5902, 366, 9050, 1002, 85, 5500, 5431, 1864, 1863, 8085,
+	51103, 49999, 45100, 10243, 49, 3495, 6667, 90, 475, 27000,
+	1503, 6881, 1500, 8021, 340, 78, 5566, 8088, 2222, 9071,
+	8899, 6005, 9876, 1501, 5102, 32774, 32773, 9101, 5679, 163,
+	648, 146, 1666, 901, 83, 9207, 8001, 8083, 5004, 3476,
+	8084, 5214, 14238, 12345, 912, 30, 2605, 2030, 6, 541,
+	8007, 3005, 4, 1248, 2500, 880, 306, 4242, 1097, 9009,
+	2525, 1086, 1088, 8291, 52822, 6101, 900, 7200, 2809, 800,
+	32775, 12000, 1083, 211, 987, 705, 20005, 711, 13783, 6969,
+	// the rest of the nmap default top 1000 ports, in port order
+	32, 43, 70, 84, 89, 99, 109, 125, 212, 256,
+	259, 301, 406, 416, 417, 425, 458, 481, 524, 545,
+	555, 616, 617, 666, 667, 668, 683, 687, 691, 700,
+	714, 720, 722, 726, 749, 765, 777, 783, 801, 843,
+	898, 903, 911, 981, 1001, 1007, 1009, 1010, 1011, 1021,
+	1045, 1046, 1057, 1061, 1063, 1070, 1072, 1073, 1075, 1076,
+	1077, 1078, 1079, 1081, 1082, 1084, 1085, 1087, 1089, 1090,
+	1091, 1092, 1093, 1094, 1095, 1096, 1098, 1099, 1102, 1104,
+	1105, 1106, 1107, 1108, 1112, 1113, 1114, 1117, 1119, 1121,
+	1122, 1123, 1124, 1126, 1130, 1131, 1132, 1137, 1138, 1141,
+	1145, 1147, 1148, 1149, 1151, 1152, 1154, 1163, 1164, 1165,
+	1166, 1169, 1174, 1175, 1183, 1185, 1186, 1187, 1192, 1198,
+	1199, 1201, 1213, 1216, 1217, 1233, 1236, 1244, 1247, 1259,
+	1271, 1272, 1277, 1287, 1296, 1300, 1301, 1309, 1310, 1322,
+	1328, 1334, 1417, 1434, 1443, 1455, 1461, 1524, 1533, 1556,
+	1580, 1583, 1594, 1600, 1641, 1658, 1687, 1688, 1718, 1719,
+	1721, 1782, 1783, 1805, 1812, 1839, 1840, 1862, 1875, 1914,
+	1947, 1971, 1972, 1974, 1984, 1999, 2003, 2013, 2020, 2021,
+	2022, 2033, 2034, 2035, 2038, 2040, 2041, 2042, 2043, 2045,
+	2046, 2047, 2048, 2068, 2099, 2100, 2106, 2111, 2119, 2126,
+	2135, 2144, 2160, 2170, 2179, 2190, 2191, 2196, 2200, 2251,
+	2260, 2288, 2323, 2366, 2381, 2382, 2393, 2394, 2399, 2492,
+	2522, 2557, 2607, 2608, 2638, 2710, 2718, 2725, 2800, 2811,
+	2875, 2909, 2910, 2920, 2968, 2998, 3003, 3006, 3007, 3011,
+	3013, 3017, 3030, 3031, 3071, 3077, 3168, 3211, 3221, 3261,
+	3300, 3301, 3322, 3323, 3324, 3325, 3351, 3367, 3369, 3370,
+	3371, 3372, 3390, 3404, 3493, 3517, 3527, 3546, 3551, 3580,
+	3659, 3737, 3766, 3784, 3800, 3801, 3809, 3814, 3826, 3827,
+	3828, 3851, 3869, 3871, 3878, 3880, 3889, 3905, 3914, 3918,
+	3920, 3945, 3971, 3995, 3998, 4003, 4004, 4005, 4006, 4111,
+	4125, 4126, 4129, 4224, 4279, 4321, 4343, 4445, 4446, 4449,
+	4550, 4567, 4848, 4900, 4998, 5030, 5033, 5054, 5061, 5080,
+	5087, 5200, 5221, 5222, 5269, 5280, 5298, 5405, 5414, 5440,
+	5510, 5544, 5560, 5633, 5678, 5718, 5730, 5802, 5810, 5811,
+	5815, 5822, 5825, 5850, 5859, 5862, 5877, 5903, 5904, 5906,
+	5907, 5910, 5911, 5915, 5922, 5925, 5950, 5952, 5959, 5960,
+	5961, 5962, 5963, 5987, 5988, 5989, 5998, 5999, 6003, 6006,
+	6007, 6009, 6025, 6100, 6106, 6123, 6129, 6156, 6346, 6389,
+	6502, 6510, 6547, 6565, 6566, 6567, 6580, 6668, 6669, 6689,
+	6692, 6699, 6779, 6788, 6792, 6839, 6901, 7002, 7004, 7007,
+	7025, 7103, 7106, 7201, 7402, 7435, 7443, 7496, 7512, 7625,
+	7627, 7676, 7741, 7777, 7778, 7800, 7911, 7920, 7921, 7999,
+	8011, 8022, 8042, 8045, 8086, 8087, 8090, 8093, 8099, 8100,
+	8180, 8181, 8200, 8222, 8254, 8290, 8292, 8300, 8333, 8383,
+	8400, 8402, 8500, 8600, 8649, 8654, 8800, 8873, 8994, 9002,
+	9003, 9010, 9011, 9040, 9080, 9081, 9091, 9099, 9103, 9110,
+	9111, 9200, 9220, 9290, 9418, 9485, 9500, 9502, 9503, 9575,
+	9618, 9666, 9877, 9878, 9898, 9900, 9917, 9929, 9943, 9944,
+	9968, 9998, 10002, 10003, 10004, 10009, 10012, 10024, 10025, 10082,
+	10180, 10215, 10566, 10616, 10617, 10621, 10626, 10628, 10629, 10778,
+	11110, 11111, 11967, 12174, 12265, 13456, 13722, 14000, 14441, 14442,
+	15002, 15003, 15004, 15660, 15742, 16000, 16001, 16012, 16016, 16018,
+	16080, 16113, 17877, 17988, 18040, 18101, 18988, 19101, 19283, 19315,
+	19350, 19780, 19801, 19842, 20000, 20031, 20221, 20222, 21571, 22939,
+	24444, 24800, 25734, 25735, 26214, 27352, 27353, 27355, 27356, 27715,
+	28201, 30000, 30718, 30951, 31038, 31337, 32772, 32776, 32777, 32778,
+	32779, 32780, 32781, 32782, 32783, 32784, 32785, 33899, 34571, 34572,
+	34573, 38292, 40193, 40911, 41511, 44176, 44442, 44443, 44501, 48080,
+	49158, 49159, 49160, 49161, 49163, 49165, 49167, 49175, 49176, 49400,
+	50002, 50003, 50006, 50300, 50389, 50500, 50636, 50800, 51493, 52673,
+	52848, 54045, 54328, 55055, 55056, 56737, 56738, 57294, 57797, 58080,
+	60020, 60443, 61532, 61900, 62078, 63331, 65129,
+}
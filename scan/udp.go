@@ -0,0 +1,127 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// probeUDP sends the payload of the port, or an empty datagram, and waits
+// for a response. ICMP port unreachable errors are reported by the connected
+// socket as connection refused.
+func (h *Scanner) probeUDP(ctx context.Context, port int) Result {
+	r := Result{
+		Host:        h.host,
+		IP:          h.ip,
+		Port:        port,
+		Protocol:    "udp",
+		Description: Description("udp", port),
+	}
+	addr := net.JoinHostPort(h.addr, strconv.Itoa(port))
+	conn, err := h.dial(ctx, "udp", addr)
+	if err != nil {
+		r.State = classifyUDP(err)
+		if r.State == Error {
+			r.Err = err
+		}
+		return r
+	}
+	defer conn.Close()
+
+	t := time.Now()
+	conn.SetDeadline(t.Add(h.timeout()))
+	if _, err := conn.Write(udpPayloads[port]); err != nil {
+		r.State = classifyUDP(err)
+		if r.State == Error {
+			r.Err = err
+		}
+		return r
+	}
+	size := h.opts.BannerSize
+	if size < 1 {
+		size = 512
+	}
+	buf := make([]byte, size)
+	n, err := conn.Read(buf)
+	r.State = classifyUDP(err)
+	if r.State == Error {
+		r.Err = err
+	}
+	if r.State == Open || r.State == Closed {
+		r.Latency = time.Since(t)
+		h.rtt.update(r.Latency)
+	}
+	if r.State == Open && h.opts.Banner {
+		r.Banner = Sanitize(buf[:n])
+	}
+	return r
+}
+
+// classifyUDP classifies the state of a port by the error of reading the
+// response. Silence is open|filtered as most services ignore unexpected
+// datagrams.
+func classifyUDP(err error) State {
+	switch {
+	case err == nil:
+		return Open
+	case timeout(err):
+		return OpenFiltered
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return Closed
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTDOWN):
+		return Unreachable
+	}
+	return Error
+}
+
+// dnsQuery builds a recursive DNS query for name
+func dnsQuery(name string, qtype byte) []byte {
+	q := []byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		if label != "" {
+			q = append(q, byte(len(label)))
+			q = append(q, label...)
+		}
+	}
+	return append(q, 0x00, 0x00, qtype, 0x00, 0x01)
+}
+
+// udpPayloads are protocol requests which make well known services answer
+var udpPayloads = map[int][]byte{
+	// DNS NS query for the root zone
+	53: dnsQuery(".", 2),
+	// TFTP read request
+	69: []byte("\x00\x01netscan\x00octet\x00"),
+	// ONC RPC portmapper NULL call
+	111: {
+		0x72, 0xfe, 0x1d, 0x13, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x01, 0x86, 0xa0, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	},
+	// NTP version 4 client request
+	123: append([]byte{0xe3}, make([]byte, 47)...),
+	// NetBIOS node status request
+	137: []byte("\x80\xf0\x00\x10\x00\x01\x00\x00\x00\x00\x00\x00\x20CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\x00\x00\x21\x00\x01"),
+	// SNMPv1 get-request of sysDescr.0 with community public
+	161: {
+		0x30, 0x29, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x1c, 0x02, 0x04, 0x13, 0x37, 0x13, 0x37, 0x02, 0x01, 0x00, 0x02,
+		0x01, 0x00, 0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02,
+		0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	},
+	// RIPv2 request for the whole routing table
+	520: append([]byte{0x01, 0x02, 0x00, 0x00}, append(make([]byte, 19), 0x10)...),
+	// MS SQL Server browser instance enumeration
+	1434: {0x02},
+	// SSDP discovery
+	1900: []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n"),
+	// mDNS service enumeration
+	5353: dnsQuery("_services._dns-sd._udp.local", 12),
+	// memcached stats with UDP frame header
+	11211: []byte("\x00\x01\x00\x00\x00\x01\x00\x00stats\r\n"),
+}
@@ -0,0 +1,69 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func udpListen(t *testing.T, echo bool) int {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if echo {
+				conn.WriteTo(append([]byte("echo "), buf[:n]...), addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestScanUDP(t *testing.T) {
+	open := udpListen(t, true)
+	silent := udpListen(t, false)
+	conn, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	closed := conn.LocalAddr().(*net.UDPAddr).Port
+	conn.Close()
+
+	opts := DefaultOptions()
+	opts.UDP = true
+	opts.ReportAll = true
+	opts.Banner = true
+	opts.Timeout = 200 * time.Millisecond
+	opts.Ports = []int{open, silent, closed}
+
+	states := make(map[int]Result)
+	for r := range Scan(context.Background(), HostList{"127.0.0.1"}, opts) {
+		states[r.Port] = r
+	}
+	if r := states[open]; r.State != Open || r.Protocol != "udp" || r.Banner != "echo" {
+		t.Errorf("open port: got %+v", r)
+	}
+	if r := states[silent]; r.State != OpenFiltered {
+		t.Errorf("silent port: got %+v", r)
+	}
+	if r := states[closed]; r.State != Closed {
+		t.Errorf("closed port: got %+v", r)
+	}
+}
+
+func TestUDPPayloads(t *testing.T) {
+	// SNMP and DNS payloads must be well formed for services to answer
+	snmp := udpPayloads[161]
+	if int(snmp[1]) != len(snmp)-2 {
+		t.Errorf("SNMP message length %d, payload has %d bytes", snmp[1], len(snmp)-2)
+	}
+	if q := dnsQuery("a.bc", 1); string(q[12:]) != "\x01a\x02bc\x00\x00\x01\x00\x01" {
+		t.Errorf("got DNS query %q", q)
+	}
+}
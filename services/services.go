@@ -0,0 +1,291 @@
+// Package services maps ports to the names and descriptions of the services
+// registered for them, by protocol and port.
+//
+// Databases are read from files in the format of /etc/services, or of
+// nmap-services where a third column holds the frequency the port is found
+// open, or from the CSV export of the IANA service name and port number
+// registry, see
+// https://www.iana.org/assignments/service-names-port-numbers/service-names-port-numbers.csv.
+package services
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // fallback services
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SystemFile is the services file of the system
+const SystemFile = "/etc/services"
+
+//go:embed services.txt
+var builtin string
+
+// Entry is a service registered for a port
+type Entry struct {
+	Name        string
+	Port        int
+	Protocol    string
+	Aliases     []string
+	Description string
+	// Frequency the port is found open from 0 to 1, 0 if unknown
+	Frequency float64
+}
+
+// String returns the name of the service followed by its description
+func (e Entry) String() string {
+	if e.Description == "" {
+		return e.Name
+	}
+	return "(" + e.Name + ") " + e.Description
+}
+
+type key struct {
+	protocol string
+	port     int
+}
+
+type nameKey struct {
+	protocol, name string
+}
+
+// DB is a service database. A DB is safe for concurrent lookups, but not
+// while it is updated.
+type DB struct {
+	entries map[key]Entry
+	names   map[nameKey]int
+}
+
+// New returns an empty database
+func New() *DB {
+	return &DB{entries: make(map[key]Entry), names: make(map[nameKey]int)}
+}
+
+var (
+	defaultDB   *DB
+	defaultOnce sync.Once
+)
+
+// Default returns the database shipped with netscan updated with the
+// SystemFile, if it can be read
+func Default() *DB {
+	defaultOnce.Do(func() {
+		db, err := Load(strings.NewReader(builtin))
+		if err != nil {
+			panic(err)
+		}
+		if sys, err := LoadFile(SystemFile); err == nil {
+			db.Update(sys)
+		}
+		defaultDB = db
+	})
+	return defaultDB
+}
+
+// LoadFile loads a database from path
+func LoadFile(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	db, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return db, nil
+}
+
+// Load a database in services or IANA CSV format. The first entry of a
+// protocol and port and the first port of a name are kept.
+func Load(r io.Reader) (*DB, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	db := New()
+	if bytes.HasPrefix(data, []byte("Service Name,")) {
+		err = db.readCSV(data)
+	} else {
+		err = db.readServices(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// readServices reads lines of
+//
+//	name  port/protocol  [frequency]  [aliases...]  [# description]
+func (db *DB) readServices(data []byte) error {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line, desc, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return fmt.Errorf("line %d: missing port of %q", n, fields[0])
+		}
+		port, protocol, ok := strings.Cut(fields[1], "/")
+		p, err := strconv.Atoi(port)
+		if !ok || err != nil || p < 0 || p > 65535 {
+			return fmt.Errorf("line %d: invalid port %q", n, fields[1])
+		}
+		e := Entry{Name: fields[0], Port: p, Protocol: strings.ToLower(protocol), Description: strings.TrimSpace(desc)}
+		aliases := fields[2:]
+		if len(aliases) > 0 && strings.Contains(aliases[0], ".") {
+			if f, err := strconv.ParseFloat(aliases[0], 64); err == nil {
+				e.Frequency = f
+				aliases = aliases[1:]
+			}
+		}
+		if len(aliases) > 0 {
+			e.Aliases = aliases
+		}
+		db.add(e)
+	}
+	return sc.Err()
+}
+
+// readCSV reads the registry columns Service Name, Port Number, Transport
+// Protocol and Description. Reserved and unassigned ports without a name
+// are skipped.
+func (db *DB) readCSV(data []byte) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	header, err := r.Read()
+	if err != nil {
+		return err
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"Service Name", "Port Number", "Transport Protocol"} {
+		if _, ok := col[name]; !ok {
+			return fmt.Errorf("missing column %q", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, port, protocol := field(record, "Service Name"), field(record, "Port Number"), field(record, "Transport Protocol")
+		if name == "" || port == "" || protocol == "" {
+			continue
+		}
+		first, last, err := portRange(port)
+		if err != nil {
+			line, _ := r.FieldPos(0)
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		desc := strings.Join(strings.Fields(field(record, "Description")), " ")
+		for p := first; p <= last; p++ {
+			db.add(Entry{Name: name, Port: p, Protocol: strings.ToLower(protocol), Description: desc})
+		}
+	}
+}
+
+func portRange(s string) (int, int, error) {
+	start, end, found := strings.Cut(s, "-")
+	if !found {
+		end = start
+	}
+	first, err1 := strconv.Atoi(start)
+	last, err2 := strconv.Atoi(end)
+	if err := errors.Join(err1, err2); err != nil || first < 0 || last > 65535 || last < first {
+		return 0, 0, fmt.Errorf("invalid port %q", s)
+	}
+	return first, last, nil
+}
+
+// add an entry unless its protocol and port or its names are known
+func (db *DB) add(e Entry) {
+	k := key{e.Protocol, e.Port}
+	if _, ok := db.entries[k]; !ok {
+		db.entries[k] = e
+	}
+	for _, name := range append([]string{e.Name}, e.Aliases...) {
+		nk := nameKey{e.Protocol, strings.ToLower(name)}
+		if _, ok := db.names[nk]; !ok {
+			db.names[nk] = e.Port
+		}
+	}
+}
+
+// Update db with the entries of other, which replace the entries and names
+// of the same protocol and port
+func (db *DB) Update(other *DB) {
+	for k, e := range other.entries {
+		db.entries[k] = e
+	}
+	for nk, port := range other.names {
+		db.names[nk] = port
+	}
+}
+
+// Len returns the number of entries
+func (db *DB) Len() int {
+	return len(db.entries)
+}
+
+// Lookup returns the service registered for port
+func (db *DB) Lookup(protocol string, port int) (Entry, bool) {
+	e, ok := db.entries[key{protocol, port}]
+	return e, ok
+}
+
+// Port returns the port of the service name or alias, ignoring case
+func (db *DB) Port(protocol, name string) (int, bool) {
+	port, ok := db.names[nameKey{protocol, strings.ToLower(name)}]
+	return port, ok
+}
+
+// Top returns the n ports of protocol most frequently found open, most
+// frequent first. It returns nil when no entry of protocol has a frequency.
+func (db *DB) Top(protocol string, n int) []int {
+	var top []Entry
+	for k, e := range db.entries {
+		if k.protocol == protocol && e.Frequency > 0 {
+			top = append(top, e)
+		}
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Frequency != top[j].Frequency {
+			return top[i].Frequency > top[j].Frequency
+		}
+		return top[i].Port < top[j].Port
+	})
+	if n > len(top) {
+		n = len(top)
+	}
+	var ports []int
+	for _, e := range top[:n] {
+		ports = append(ports, e.Port)
+	}
+	return ports
+}
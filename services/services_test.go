@@ -0,0 +1,96 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	db, err := Load(strings.NewReader(`# comment
+ssh		22/tcp				# SSH Remote Login Protocol
+exec		512/tcp
+biff		512/udp		comsat
+exec		512/tcp		# duplicate
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := db.Lookup("tcp", 22); e.String() != "(ssh) SSH Remote Login Protocol" {
+		t.Errorf("22/tcp: got %q", e)
+	}
+	if e, _ := db.Lookup("udp", 512); e.Name != "biff" || e.Description != "" {
+		t.Errorf("512/udp: got %+v", e)
+	}
+	if e, _ := db.Lookup("tcp", 512); e.Name != "exec" || e.Description != "" {
+		t.Errorf("512/tcp: got %+v", e)
+	}
+	if _, ok := db.Lookup("udp", 22); ok {
+		t.Errorf("22/udp: expected no entry")
+	}
+	if port, ok := db.Port("udp", "COMSAT"); !ok || port != 512 {
+		t.Errorf("comsat: got %d", port)
+	}
+
+	for _, bad := range []string{"ssh", "ssh 22", "ssh x/tcp", "ssh 65536/tcp"} {
+		if _, err := Load(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	db, err := Load(strings.NewReader("\xef\xbb\xbfService Name,Port Number,Transport Protocol,Description,Assignee\n" +
+		",0,tcp,Reserved,\n" +
+		"http,80,tcp,World Wide Web HTTP,\n" +
+		"http,80,udp,World Wide Web HTTP,\n" +
+		"x11,6000-6063,tcp,\"X Window\n System\",\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 66 {
+		t.Errorf("got %d entries", db.Len())
+	}
+	if e, _ := db.Lookup("tcp", 6010); e.String() != "(x11) X Window System" {
+		t.Errorf("6010/tcp: got %q", e)
+	}
+	if _, err := Load(strings.NewReader("Service Name,Port Number,Transport Protocol\nx,1-a,tcp\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
+
+func TestTop(t *testing.T) {
+	db, err := Load(strings.NewReader(`
+telnet	23/tcp	0.221265
+http	80/tcp	0.484143	# World Wide Web HTTP
+https	443/tcp	0.208669
+snmp	161/udp	0.433467
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top := db.Top("tcp", 2); !reflect.DeepEqual(top, []int{80, 23}) {
+		t.Errorf("got %v", top)
+	}
+	if top := db.Top("udp", 5); !reflect.DeepEqual(top, []int{161}) {
+		t.Errorf("got %v", top)
+	}
+	if top := db.Top("sctp", 5); top != nil {
+		t.Errorf("got %v", top)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	db, _ := Load(strings.NewReader(builtin))
+	if db.Len() < 250 {
+		t.Errorf("builtin: got %d entries", db.Len())
+	}
+	override, _ := Load(strings.NewReader("myapp 8080/tcp # internal app"))
+	db.Update(override)
+	if e, _ := db.Lookup("tcp", 8080); e.Name != "myapp" {
+		t.Errorf("8080/tcp: got %+v", e)
+	}
+	if port, _ := db.Port("tcp", "myapp"); port != 8080 {
+		t.Errorf("myapp: got %d", port)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// notifyStatus does nothing where there is no SIGUSR1
+func notifyStatus(c chan<- os.Signal) {}
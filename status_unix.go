@@ -0,0 +1,14 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyStatus relays SIGUSR1 to c
+func notifyStatus(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
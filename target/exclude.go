@@ -0,0 +1,184 @@
+package target
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// maxRuns limits the address ranges an octet range is split into
+const maxRuns = 1 << 16
+
+// Exclusions are the hosts, address ranges and subnets removed from a List
+// by Exclude. Their ranges are not limited to MaxRange addresses.
+type Exclusions struct {
+	ranges []*ipRange
+	names  map[string]bool
+}
+
+// Add the items of the comma separated target expression expr
+func (x *Exclusions) Add(expr string) error {
+	for _, item := range strings.Split(expr, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			if err := x.addItem(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadFile adds the items of file path in the format of Read, "-" reads
+// standard input
+func (x *Exclusions) ReadFile(path string) error {
+	return readFile(path, x.addItem)
+}
+
+func (x *Exclusions) addItem(item string) error {
+	b, err := parseItem(item)
+	if err != nil {
+		return err
+	}
+	switch b := b.(type) {
+	case hostName:
+		if x.names == nil {
+			x.names = make(map[string]bool)
+		}
+		x.names[strings.ToLower(string(b))] = true
+	case *ipRange:
+		x.ranges = append(x.ranges, b)
+	case *octetRange:
+		runs, err := b.ranges()
+		if err != nil {
+			return err
+		}
+		x.ranges = append(x.ranges, runs...)
+	}
+	return nil
+}
+
+// Exclude removes the hosts of x from the list. Address ranges are split
+// around the excluded addresses and excluded host names are dropped, so
+// excluded hosts are never generated.
+func (l *List) Exclude(x *Exclusions) error {
+	sort.Slice(x.ranges, func(a, b int) bool { return x.ranges[a].first.less(x.ranges[b].first) })
+	blocks, ports := l.blocks, l.ports
+	*l = List{}
+	for i, b := range blocks {
+		port := ports[i]
+		switch b := b.(type) {
+		case hostName:
+			if !x.names[strings.ToLower(string(b))] {
+				l.add(b, port)
+			}
+		case *resolvedName:
+			if x.names[strings.ToLower(b.name)] {
+				continue
+			}
+			var addrs []netip.Addr
+			for _, a := range b.addrs {
+				if !x.contains(a) {
+					addrs = append(addrs, a)
+				}
+			}
+			if len(addrs) > 0 {
+				l.add(&resolvedName{name: b.name, addrs: addrs}, port)
+			}
+		case *ipRange:
+			for _, r := range b.subtract(x.ranges) {
+				l.add(r, port)
+			}
+		case *octetRange:
+			if !b.overlaps(x.ranges) {
+				l.add(b, port)
+				continue
+			}
+			runs, err := b.ranges()
+			if err != nil {
+				return err
+			}
+			for _, run := range runs {
+				for _, r := range run.subtract(x.ranges) {
+					l.add(r, port)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// contains reports whether a is in the excluded ranges
+func (x *Exclusions) contains(a netip.Addr) bool {
+	v := addrToInt(a)
+	for _, e := range x.ranges {
+		if e.v4 == a.Is4() && e.zone == a.Zone() && !v.less(e.first) && !e.last.less(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// subtract returns the parts of r outside of the ranges ex, which are
+// sorted by their first address
+func (r *ipRange) subtract(ex []*ipRange) []*ipRange {
+	var parts []*ipRange
+	cur := r.first
+	for _, e := range ex {
+		if e.v4 != r.v4 || e.zone != r.zone || e.last.less(cur) {
+			continue
+		}
+		if r.last.less(e.first) {
+			break
+		}
+		if cur.less(e.first) {
+			parts = append(parts, &ipRange{first: cur, last: e.first.sub(uint128{0, 1}), v4: r.v4, zone: r.zone})
+		}
+		if !e.last.less(r.last) {
+			return parts
+		}
+		cur = e.last.add(1)
+	}
+	return append(parts, &ipRange{first: cur, last: r.last, v4: r.v4, zone: r.zone})
+}
+
+// overlaps reports whether any of the ranges ex holds addresses between the
+// lowest and highest address of o
+func (o *octetRange) overlaps(ex []*ipRange) bool {
+	first := addrToInt(netip.AddrFrom4(o.lo))
+	last := addrToInt(netip.AddrFrom4(o.hi))
+	for _, e := range ex {
+		if e.v4 && e.zone == "" && !e.last.less(first) && !last.less(e.first) {
+			return true
+		}
+	}
+	return false
+}
+
+// ranges splits o into ranges of consecutive addresses, one for each
+// combination of the first three octets
+func (o *octetRange) ranges() ([]*ipRange, error) {
+	runLen := uint64(o.hi[3]-o.lo[3]) + 1
+	n := o.len() / runLen
+	if n > maxRuns {
+		return nil, fmt.Errorf("octet range %s is too large to exclude from", o)
+	}
+	runs := make([]*ipRange, 0, n)
+	for i := uint64(0); i < n; i++ {
+		r := &ipRange{first: addrToInt(o.host(i * runLen).Addr), v4: true}
+		r.last = r.first.add(runLen - 1)
+		runs = append(runs, r)
+	}
+	return runs, nil
+}
+
+func (o *octetRange) String() string {
+	parts := make([]string, 4)
+	for i := range parts {
+		parts[i] = fmt.Sprint(o.lo[i])
+		if o.hi[i] != o.lo[i] {
+			parts[i] += fmt.Sprintf("-%d", o.hi[i])
+		}
+	}
+	return strings.Join(parts, ".")
+}
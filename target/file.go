@@ -0,0 +1,62 @@
+package target
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Read a list of targets with one or more comma separated items per line.
+// Empty lines and comments starting with "#" are ignored.
+func Read(r io.Reader) (*List, error) {
+	l := &List{}
+	if err := readItems(r, l.addItem); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ReadFile reads the targets of file path, "-" reads standard input
+func ReadFile(path string) (*List, error) {
+	l := &List{}
+	if err := readFile(path, l.addItem); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// readItems calls add for every item of r
+func readItems(r io.Reader, add func(item string) error) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		for _, item := range strings.Split(line, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			if err := add(item); err != nil {
+				return fmt.Errorf("line %d: %v", n, err)
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// readFile calls add for every item of file path, "-" is standard input
+func readFile(path string, add func(item string) error) error {
+	if path == "-" {
+		return readItems(os.Stdin, add)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := readItems(f, add); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
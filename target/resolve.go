@@ -0,0 +1,114 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// resolvedName is a host name with its addresses
+type resolvedName struct {
+	name  string
+	addrs []netip.Addr
+}
+
+func (r *resolvedName) len() uint64 {
+	return uint64(len(r.addrs))
+}
+
+func (r *resolvedName) host(i uint64) Host {
+	return Host{Name: r.name, Addr: r.addrs[i]}
+}
+
+// Resolve the IPv4 and IPv6 addresses of the host names in the list with
+// resolver, nil uses the default resolver. A name is scanned at its first
+// IPv4 address, or its first address if it has none, or at all its
+// addresses with all. Names which can not be resolved are removed from the
+// list and reported in the returned error.
+func (l *List) Resolve(ctx context.Context, resolver *net.Resolver, all bool) error {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	var errs []error
+	blocks, ports := l.blocks, l.ports
+	*l = List{}
+	for i, b := range blocks {
+		name, ok := b.(hostName)
+		if !ok {
+			l.add(b, ports[i])
+			continue
+		}
+		addrs, err := resolver.LookupNetIP(ctx, "ip", string(name))
+		if err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("no addresses for %s", name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range addrs {
+			addrs[i] = addrs[i].Unmap()
+		}
+		if !all {
+			addrs = []netip.Addr{preferred(addrs)}
+		}
+		l.add(&resolvedName{name: string(name), addrs: addrs}, ports[i])
+	}
+	return errors.Join(errs...)
+}
+
+// Resolve the addresses of the host names in x with resolver, nil uses the
+// default resolver. All addresses of a name are excluded. Names which can
+// not be resolved are only matched literally and reported in the returned
+// error.
+func (x *Exclusions) Resolve(ctx context.Context, resolver *net.Resolver) error {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	var errs []error
+	for name := range x.names {
+		addrs, err := resolver.LookupNetIP(ctx, "ip", name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, a := range addrs {
+			a = a.Unmap()
+			v := addrToInt(a)
+			x.ranges = append(x.ranges, &ipRange{first: v, last: v, v4: a.Is4(), zone: a.Zone()})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// preferred returns the first IPv4 address, or the first address
+func preferred(addrs []netip.Addr) netip.Addr {
+	for _, a := range addrs {
+		if a.Is4() {
+			return a
+		}
+	}
+	return addrs[0]
+}
+
+// NewResolver returns a resolver querying the DNS server at addr, the port
+// defaults to 53
+func NewResolver(addr string) (*net.Resolver, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	if _, err := netip.ParseAddr(host); err != nil {
+		return nil, fmt.Errorf("invalid DNS server %q: not an IP address", addr)
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
@@ -0,0 +1,372 @@
+// Package target parses target expressions into the list of hosts to scan.
+//
+// A target expression is a comma separated list of items, where each item is
+// one of
+//
+//	192.168.0.1                  single address
+//	192.168.0.0/24               CIDR block
+//	192.168.0.1:192.168.1.255    address range (also written with "-")
+//	10.0.1-3.1-254               octet ranges
+//	2001:db8::1, [fe80::1%eth0]  IPv6 address, optionally bracketed and zoned
+//	[2001:db8::1]:22             bracketed address with the only port to scan
+//	                             on it, see Host.Port
+//	2001:db8::/120               IPv6 prefix
+//	2001:db8::1-2001:db8::ff     IPv6 address range
+//	example.com                  host name, see List.Resolve
+//
+// Ranges are limited to MaxRange addresses per item. Hosts are generated on
+// demand from the parsed ranges, a List takes the same memory for a single
+// address and for a /8.
+package target
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MaxRange is the largest number of addresses a single target item may hold
+const MaxRange = 1 << 32
+
+// List of hosts parsed from a target expression
+type List struct {
+	blocks []block
+	// offsets[i] is the index of the first host of blocks[i]
+	offsets []uint64
+	size    uint64
+	// ports[i] is the port of blocks[i] of a bracketed host:port item, 0
+	// for other items
+	ports []int
+}
+
+// block is a set of hosts produced by a single target item
+type block interface {
+	len() uint64
+	host(i uint64) Host
+}
+
+// Host is a host of a List. Addr is the address to scan, it is not valid
+// for host names which are not resolved. Port is the only port to scan on
+// hosts of [address]:port items, 0 for the others.
+type Host struct {
+	Name string     `json:"name"`
+	Addr netip.Addr `json:"addr"`
+	Port int        `json:"port,omitempty"`
+}
+
+// NewHost returns the Host name at address ip, which may be nil. The zone
+// of an IPv6 name is kept.
+func NewHost(name string, ip net.IP) Host {
+	h := Host{Name: name}
+	if a, ok := netip.AddrFromSlice(ip); ok {
+		_, zone, _ := strings.Cut(name, "%")
+		h.Addr = a.Unmap().WithZone(zone)
+	}
+	return h
+}
+
+// Hosts is a list of single hosts
+type Hosts []Host
+
+// Len returns the number of hosts
+func (h Hosts) Len() uint64 {
+	return uint64(len(h))
+}
+
+// Host returns host i
+func (h Hosts) Host(i uint64) Host {
+	return h[i]
+}
+
+// Parse target expression expr
+func Parse(expr string) (*List, error) {
+	l := &List{}
+	for _, item := range strings.Split(expr, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if err := l.addItem(item); err != nil {
+			return nil, err
+		}
+	}
+	if len(l.blocks) == 0 {
+		return nil, fmt.Errorf("no targets in %q", expr)
+	}
+	return l, nil
+}
+
+// addItem adds the hosts of a target item
+func (l *List) addItem(item string) error {
+	port := 0
+	if host, p, ok := bracketPort(item); ok {
+		var err error
+		port, err = strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid target %q: bad port %q", item, p)
+		}
+		item = host
+	}
+	b, err := parseItem(item)
+	if err != nil {
+		return err
+	}
+	if r, ok := b.(*ipRange); ok {
+		if n := r.last.sub(r.first); n.hi != 0 || n.lo >= MaxRange {
+			return fmt.Errorf("invalid target %q: range is larger than %d addresses", item, uint64(MaxRange))
+		}
+	}
+	l.add(b, port)
+	return nil
+}
+
+// add the hosts of b, scanned only on port unless it is 0
+func (l *List) add(b block, port int) {
+	l.blocks = append(l.blocks, b)
+	l.offsets = append(l.offsets, l.size)
+	l.ports = append(l.ports, port)
+	l.size += b.len()
+}
+
+// Append the hosts of other to the list
+func (l *List) Append(other *List) {
+	for i, b := range other.blocks {
+		l.add(b, other.ports[i])
+	}
+}
+
+// bracketPort splits a "[host]:port" item
+func bracketPort(item string) (host, port string, ok bool) {
+	if !strings.HasPrefix(item, "[") {
+		return item, "", false
+	}
+	i := strings.LastIndex(item, "]:")
+	if i < 0 {
+		return item, "", false
+	}
+	return item[:i+1], item[i+2:], true
+}
+
+// Len returns the number of hosts in the list
+func (l *List) Len() uint64 {
+	return l.size
+}
+
+// Fingerprint identifies the hosts of the list and their order, it changes
+// when a file or a name resolves to other hosts
+func (l *List) Fingerprint() string {
+	h := fnv.New64a()
+	for n, b := range l.blocks {
+		fmt.Fprintf(h, "%d %d", b.len(), l.ports[n])
+		if r, ok := b.(*resolvedName); ok {
+			fmt.Fprint(h, " ", r.name, r.addrs)
+		} else {
+			// the first and last host pin down a range
+			fmt.Fprint(h, " ", b.host(0), b.host(b.len()-1))
+		}
+		fmt.Fprintln(h)
+	}
+	return fmt.Sprintf("%d/%016x", l.size, h.Sum64())
+}
+
+// Host returns host i of the list, 0 <= i < Len()
+func (l *List) Host(i uint64) Host {
+	n := sort.Search(len(l.offsets), func(n int) bool { return l.offsets[n] > i }) - 1
+	h := l.blocks[n].host(i - l.offsets[n])
+	h.Port = l.ports[n]
+	return h
+}
+
+func parseItem(item string) (block, error) {
+	if strings.Contains(item, "/") {
+		return parsePrefix(item)
+	}
+	if start, end, ok := cut(item, "-"); ok {
+		if first, err := parseAddr(start); err == nil {
+			if last, err := parseAddr(end); err == nil {
+				return newRange(item, first, last)
+			}
+		}
+	}
+	if a, err := parseAddr(item); err == nil {
+		return newRange(item, a, a)
+	}
+	// legacy IPv4 start:end range
+	if start, end, ok := cut(item, ":"); ok {
+		first, err := parseAddr(start)
+		if err != nil || !first.Is4() {
+			return nil, fmt.Errorf("invalid target %q: %q is not an IPv4 address", item, start)
+		}
+		last, err := parseAddr(end)
+		if err != nil || !last.Is4() {
+			return nil, fmt.Errorf("invalid target %q: %q is not an IPv4 address", item, end)
+		}
+		return newRange(item, first, last)
+	}
+	if isOctets(item) {
+		return parseOctets(item)
+	}
+	if isHostName(item) {
+		return hostName(item), nil
+	}
+	return nil, fmt.Errorf("invalid target %q", item)
+}
+
+// cut splits s around the only occurrence of sep
+func cut(s, sep string) (before, after string, found bool) {
+	if strings.Count(s, sep) != 1 {
+		return s, "", false
+	}
+	i := strings.Index(s, sep)
+	return s[:i], s[i+len(sep):], true
+}
+
+// parseAddr parses an IPv4 or IPv6 address, which may be bracketed
+func parseAddr(s string) (netip.Addr, error) {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return a, err
+	}
+	return a.Unmap(), nil
+}
+
+func parsePrefix(item string) (block, error) {
+	p, err := netip.ParsePrefix(strings.NewReplacer("[", "", "]", "").Replace(item))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target %q: not a CIDR block", item)
+	}
+	p = p.Masked()
+	first := addrToInt(p.Addr())
+	hostBits := p.Addr().BitLen() - p.Bits()
+	return newRange(item, p.Addr(), first.or(hostBits).addr(p.Addr().Is4()))
+}
+
+func newRange(item string, first, last netip.Addr) (block, error) {
+	if first.Is4() != last.Is4() {
+		return nil, fmt.Errorf("invalid target %q: mixed IPv4 and IPv6 range", item)
+	}
+	if first.Zone() != last.Zone() && last.Zone() != "" {
+		return nil, fmt.Errorf("invalid target %q: range zones differ", item)
+	}
+	r := &ipRange{
+		first: addrToInt(first),
+		last:  addrToInt(last),
+		v4:    first.Is4(),
+		zone:  first.Zone(),
+	}
+	if r.last.less(r.first) {
+		return nil, fmt.Errorf("invalid target %q: range end is before start", item)
+	}
+	return r, nil
+}
+
+// ipRange is an inclusive range of IPv4 or IPv6 addresses
+type ipRange struct {
+	first, last uint128
+	v4          bool
+	zone        string
+}
+
+func (r *ipRange) len() uint64 {
+	return r.last.sub(r.first).lo + 1
+}
+
+func (r *ipRange) host(i uint64) Host {
+	a := r.first.add(i).addr(r.v4).WithZone(r.zone)
+	return Host{Name: a.String(), Addr: a}
+}
+
+// octetRange is an IPv4 address pattern with a range for each octet
+type octetRange struct {
+	lo, hi [4]uint8
+}
+
+func isOctets(item string) bool {
+	parts := strings.Split(item, ".")
+	if len(parts) != 4 {
+		return false
+	}
+	for _, p := range parts {
+		if strings.Trim(p, "0123456789-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseOctets(item string) (block, error) {
+	o := &octetRange{}
+	for i, p := range strings.Split(item, ".") {
+		lo, hi, ok := cut(p, "-")
+		if !ok {
+			hi = lo
+		}
+		l, err := strconv.ParseUint(lo, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: bad octet %q", item, p)
+		}
+		h, err := strconv.ParseUint(hi, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target %q: bad octet %q", item, p)
+		}
+		if h < l {
+			return nil, fmt.Errorf("invalid target %q: octet range %q is reversed", item, p)
+		}
+		o.lo[i], o.hi[i] = uint8(l), uint8(h)
+	}
+	return o, nil
+}
+
+func (o *octetRange) len() uint64 {
+	n := uint64(1)
+	for i := range o.lo {
+		n *= uint64(o.hi[i]-o.lo[i]) + 1
+	}
+	return n
+}
+
+// host treats the octets as a mixed radix number with the last octet
+// changing fastest
+func (o *octetRange) host(i uint64) Host {
+	var ip [4]byte
+	for k := 3; k >= 0; k-- {
+		n := uint64(o.hi[k]-o.lo[k]) + 1
+		ip[k] = o.lo[k] + uint8(i%n)
+		i /= n
+	}
+	a := netip.AddrFrom4(ip)
+	return Host{Name: a.String(), Addr: a}
+}
+
+// hostName is a host name which is resolved when dialed, unless resolved
+// by List.Resolve
+type hostName string
+
+func isHostName(s string) bool {
+	if len(s) > 253 || s[0] == '-' || s[0] == '.' {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (h hostName) len() uint64 {
+	return 1
+}
+
+func (h hostName) host(uint64) Host {
+	return Host{Name: string(h)}
+}
@@ -0,0 +1,270 @@
+package target
+
+import (
+	"context"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr  string
+		hosts []string
+	}{
+		{"10.0.0.1", []string{"10.0.0.1"}},
+		{"10.0.0.8/30", []string{"10.0.0.8", "10.0.0.9", "10.0.0.10", "10.0.0.11"}},
+		{"127.0.255.254:127.1.0.1", []string{"127.0.255.254", "127.0.255.255", "127.1.0.0", "127.1.0.1"}},
+		{"10.0.0.1-10.0.0.2", []string{"10.0.0.1", "10.0.0.2"}},
+		{"10.0.1-2.1-2", []string{"10.0.1.1", "10.0.1.2", "10.0.2.1", "10.0.2.2"}},
+		{"10.0.0.1-2, example.com", []string{"10.0.0.1", "10.0.0.2", "example.com"}},
+		{"255.255.255.255/32", []string{"255.255.255.255"}},
+		{"::1", []string{"::1"}},
+		{"[fe80::1%eth0]", []string{"fe80::1%eth0"}},
+		{"2001:db8::fe-2001:db8::101", []string{"2001:db8::fe", "2001:db8::ff", "2001:db8::100", "2001:db8::101"}},
+		{"[2001:db8::ffff:ffff:ffff:ffff]-[2001:db8:0:1::]", []string{"2001:db8::ffff:ffff:ffff:ffff", "2001:db8:0:1::"}},
+		{"2001:db8::5/126", []string{"2001:db8::4", "2001:db8::5", "2001:db8::6", "2001:db8::7"}},
+		{"::ffff:10.0.0.1", []string{"10.0.0.1"}},
+		{"[2001:db8::1]:22", []string{"2001:db8::1"}},
+		{"[10.0.0.1]:80", []string{"10.0.0.1"}},
+	}
+	for _, test := range tests {
+		l, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("%q: %v", test.expr, err)
+			continue
+		}
+		if hosts := names(l); !reflect.DeepEqual(hosts, test.hosts) || l.Len() != uint64(len(hosts)) {
+			t.Errorf("%q: got %v, want %v", test.expr, hosts, test.hosts)
+		}
+	}
+
+	if l, _ := Parse("0.0.0.0/0"); l.Len() != 1<<32 {
+		t.Errorf("0.0.0.0/0: got %d hosts", l.Len())
+	}
+	if l, _ := Parse("2001:db8::/120"); l.Len() != 256 {
+		t.Errorf("2001:db8::/120: got %d hosts", l.Len())
+	}
+	// ports of bracketed items apply to their hosts only
+	l, _ := Parse("[::1]:22,10.0.0.1,[fe80::1%eth0]:443")
+	if ports := []int{l.Host(0).Port, l.Host(1).Port, l.Host(2).Port}; !reflect.DeepEqual(ports, []int{22, 0, 443}) {
+		t.Errorf("got ports %v", ports)
+	}
+	x := &Exclusions{}
+	x.Add("10.0.0.1")
+	l.Exclude(x)
+	if l.Len() != 2 || l.Host(1).Port != 443 {
+		t.Errorf("port lost by Exclude: %+v", l.Host(1))
+	}
+}
+
+func TestHost(t *testing.T) {
+	l, err := Parse("10.0.0.1-2,example.com,10.0.1-2.1-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.2", "example.com", "10.0.1.1", "10.0.1.2", "10.0.2.1", "10.0.2.2"}
+	if hosts := names(l); !reflect.DeepEqual(hosts, want) {
+		t.Errorf("got %v, want %v", hosts, want)
+	}
+
+	l, _ = Parse("10.0.0.0/8")
+	if host := l.Host(l.Len() - 1); host.Name != "10.255.255.255" {
+		t.Errorf("last host of 10.0.0.0/8 is %s", host.Name)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := func(expr string) string {
+		l, err := Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l.Fingerprint()
+	}
+	if fingerprint("10.0.0.0/24,example.com") != fingerprint("10.0.0.0-10.0.0.255,example.com") {
+		t.Error("same hosts differ")
+	}
+	if fingerprint("[::1]:80") == fingerprint("[::1]:81") {
+		t.Error("host ports are not in the fingerprint")
+	}
+	for _, expr := range []string{"10.0.0.0/25,example.com", "10.0.0.0/24,example.org", "example.com,10.0.0.0/24", "10.0.0.0/24,example.com,[::1]:80"} {
+		if fingerprint(expr) == fingerprint("10.0.0.0/24,example.com") {
+			t.Errorf("%s: same fingerprint", expr)
+		}
+	}
+
+	l, _ := Parse("localhost")
+	before := l.Fingerprint()
+	resolver, _ := NewResolver("127.0.0.1:1")
+	l.Resolve(context.Background(), resolver, false)
+	if l.Fingerprint() == before {
+		t.Error("resolved addresses are not in the fingerprint")
+	}
+}
+
+// names returns the names of all hosts of l
+func names(l *List) []string {
+	hosts := make([]string, 0, l.Len())
+	for i := uint64(0); i < l.Len(); i++ {
+		hosts = append(hosts, l.Host(i).Name)
+	}
+	return hosts
+}
+
+// ip4Table materialises the addresses from first to last, as targets were
+// before they were generated on demand
+func ip4Table(first, last netip.Addr) []string {
+	var table []string
+	for a := first; a.Compare(last) <= 0; a = a.Next() {
+		table = append(table, a.String())
+	}
+	return table
+}
+
+// a /16 is generated on demand with constant memory, materialising it takes
+// a string per address
+func BenchmarkTable(b *testing.B) {
+	first, last := netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.255.255")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for _, host := range ip4Table(first, last) {
+			_ = host
+		}
+	}
+}
+
+func BenchmarkHost(b *testing.B) {
+	l, _ := Parse("10.0.0.0/16")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := uint64(0); i < l.Len(); i++ {
+			_ = l.Host(i)
+		}
+	}
+}
+
+// time until the first host of a /16 can be scanned
+func BenchmarkFirstHost(b *testing.B) {
+	first, last := netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.255.255")
+	l, _ := Parse("10.0.0.0/16")
+	b.Run("Table", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			_ = ip4Table(first, last)[0]
+		}
+	})
+	b.Run("Host", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			_ = l.Host(0)
+		}
+	})
+}
+
+func TestRead(t *testing.T) {
+	l, err := Read(strings.NewReader("# targets\n10.0.0.1\n\n10.0.1.0/31, example.com # web\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1", "10.0.1.0", "10.0.1.1", "example.com"}
+	if hosts := names(l); !reflect.DeepEqual(hosts, want) {
+		t.Errorf("got %v, want %v", hosts, want)
+	}
+	if _, err := Read(strings.NewReader("10.0.0.1\nbad host\n")); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	tests := []struct {
+		targets, exclude string
+		hosts            []string
+	}{
+		{"10.0.0.0/29", "10.0.0.2-10.0.0.3,10.0.0.6/31", []string{"10.0.0.0", "10.0.0.1", "10.0.0.4", "10.0.0.5"}},
+		{"10.0.0.0/30", "10.0.0.0/24", nil},
+		{"10.0.0.1-2,example.com", "EXAMPLE.com,10.0.0.1", []string{"10.0.0.2"}},
+		{"10.0.1-2.1-3", "10.0.2.2,10.0.3.0/24", []string{"10.0.1.1", "10.0.1.2", "10.0.1.3", "10.0.2.1", "10.0.2.3"}},
+		{"10.0.1-2.1-2", "10.0.0.0/31", []string{"10.0.1.1", "10.0.1.2", "10.0.2.1", "10.0.2.2"}},
+		{"2001:db8::/126,10.0.0.1", "::/0,10.0.0-2.1", []string{}},
+		{"255.255.255.254/31", "255.255.255.255", []string{"255.255.255.254"}},
+	}
+	for _, test := range tests {
+		l, _ := Parse(test.targets)
+		x := &Exclusions{}
+		if err := x.Add(test.exclude); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Exclude(x); err != nil {
+			t.Errorf("%q: %v", test.exclude, err)
+			continue
+		}
+		hosts := names(l)
+		if len(hosts) != len(test.hosts) || len(hosts) > 0 && !reflect.DeepEqual(hosts, test.hosts) || l.Len() != uint64(len(hosts)) {
+			t.Errorf("%q without %q: got %v, want %v", test.targets, test.exclude, hosts, test.hosts)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	// host names are looked up in /etc/hosts before asking the server
+	resolver, err := NewResolver("127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := Parse("10.0.0.1,localhost,nosuch.invalid")
+	if err := l.Resolve(context.Background(), resolver, false); err == nil || !strings.Contains(err.Error(), "nosuch.invalid") {
+		t.Errorf("expected error for nosuch.invalid, got %v", err)
+	}
+	if l.Len() != 2 {
+		t.Fatalf("expected 2 hosts, got %v", names(l))
+	}
+	if h := l.Host(1); h.Name != "localhost" || !h.Addr.IsLoopback() {
+		t.Errorf("unexpected host %+v", h)
+	}
+
+	x := &Exclusions{}
+	x.Add("127.0.0.0/8,::1")
+	l.Exclude(x)
+	if hosts := names(l); len(hosts) != 1 || hosts[0] != "10.0.0.1" {
+		t.Errorf("expected localhost excluded, got %v", hosts)
+	}
+
+	// excluded names are resolved too
+	l, _ = Parse("127.0.0.1,10.0.0.1")
+	x = &Exclusions{}
+	x.Add("localhost,nosuch.invalid")
+	if err := x.Resolve(context.Background(), resolver); err == nil || !strings.Contains(err.Error(), "nosuch.invalid") {
+		t.Errorf("expected error for nosuch.invalid, got %v", err)
+	}
+	l.Exclude(x)
+	if hosts := names(l); len(hosts) != 1 || hosts[0] != "10.0.0.1" {
+		t.Errorf("expected localhost excluded by name, got %v", hosts)
+	}
+
+	if _, err := NewResolver("dns.example.com"); err == nil {
+		t.Error("expected error for DNS server name")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"10.0.0.2:10.0.0.1",
+		"10.0.0.1:nohost",
+		"10.0.0.256/24",
+		"10.0.3-1.1",
+		"10.0.0.1-300",
+		"bad host",
+		"10.0.0.1-2001:db8::1",
+		"2001:db8::/64",
+		"2001:db8::2-2001:db8::1",
+		"[::1]:0",
+		"[::1]:ssh",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package target
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"net/netip"
+)
+
+// uint128 is an IPv6 address, or an IPv4 mapped IPv6 address, as integer
+type uint128 struct {
+	hi, lo uint64
+}
+
+func addrToInt(a netip.Addr) uint128 {
+	b := a.As16()
+	return uint128{
+		hi: binary.BigEndian.Uint64(b[:8]),
+		lo: binary.BigEndian.Uint64(b[8:]),
+	}
+}
+
+func (u uint128) addr(v4 bool) netip.Addr {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], u.hi)
+	binary.BigEndian.PutUint64(b[8:], u.lo)
+	a := netip.AddrFrom16(b)
+	if v4 {
+		return a.Unmap()
+	}
+	return a
+}
+
+func (u uint128) add(n uint64) uint128 {
+	lo, carry := bits.Add64(u.lo, n, 0)
+	return uint128{u.hi + carry, lo}
+}
+
+func (u uint128) sub(v uint128) uint128 {
+	lo, borrow := bits.Sub64(u.lo, v.lo, 0)
+	return uint128{u.hi - v.hi - borrow, lo}
+}
+
+func (u uint128) less(v uint128) bool {
+	return u.hi < v.hi || u.hi == v.hi && u.lo < v.lo
+}
+
+// or sets the lowest n bits of u
+func (u uint128) or(n int) uint128 {
+	switch {
+	case n >= 128:
+		return uint128{^uint64(0), ^uint64(0)}
+	case n >= 64:
+		return uint128{u.hi | (1<<uint(n-64) - 1), ^uint64(0)}
+	}
+	return uint128{u.hi, u.lo | (1<<uint(n) - 1)}
+}